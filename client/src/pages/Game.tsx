@@ -83,6 +83,11 @@ export const Game: React.FC = () => {
       }
     };
 
+    // the board was sent while the lobby was showing; ask for it again
+    if (ws.readyState === WebSocket.OPEN) {
+      ws.send(JSON.stringify({ username : username, code: code, Item: "RESYNC"}));
+    }
+
     return () => { ws.onmessage = null; }
   }, [ws]);
 
@@ -0,0 +1,38 @@
+/*
+Package clock lets game timing be swapped out. Games use Real in production;
+tests use a Fake and advance it by hand, so a full lobby-to-leaderboard
+flow runs in milliseconds.
+*/
+package clock
+
+import "time"
+
+// A Clock tells the time and makes tickers.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// A Ticker is a time.Ticker whose channel is behind a method.
+type Ticker interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+// Real is the system clock.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
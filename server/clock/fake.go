@@ -0,0 +1,123 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+Fake is a Clock that only moves when Advance is called. Its tickers deliver
+every tick they owe, in order, and each send waits for the receiver, so once
+Advance returns the ticks have been taken off their channels.
+*/
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	changed chan struct{} // closes and is replaced whenever tickers are added
+}
+
+// NewFake returns a fake clock set to an arbitrary fixed time.
+func NewFake() *Fake {
+	return &Fake{
+		now:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		changed: make(chan struct{}),
+	}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{
+		clock:  f,
+		c:      make(chan time.Time),
+		stop:   make(chan struct{}),
+		period: d,
+		next:   f.now.Add(d),
+	}
+	f.tickers = append(f.tickers, t)
+	close(f.changed)
+	f.changed = make(chan struct{})
+	return t
+}
+
+// Advance moves the clock forward by d, firing every tick that comes due.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	f.mu.Unlock()
+	for {
+		f.mu.Lock()
+		var due *fakeTicker
+		for _, t := range f.tickers {
+			if !t.next.After(end) && (due == nil || t.next.Before(due.next)) {
+				due = t
+			}
+		}
+		if due == nil {
+			f.now = end
+			f.mu.Unlock()
+			return
+		}
+		f.now = due.next
+		due.next = due.next.Add(due.period)
+		now := f.now
+		f.mu.Unlock()
+
+		select {
+		case due.c <- now:
+		case <-due.stop:
+		}
+	}
+}
+
+// WaitForTickers blocks until at least n tickers are running.
+func (f *Fake) WaitForTickers(n int) {
+	for {
+		f.mu.Lock()
+		count, changed := len(f.tickers), f.changed
+		f.mu.Unlock()
+		if count >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	c      chan time.Time
+	stop   chan struct{}
+	period time.Duration
+	next   time.Time // guarded by clock.mu
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.period = d
+	t.next = t.clock.now.Add(d)
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, other := range t.clock.tickers {
+		if other == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			close(t.stop)
+			return
+		}
+	}
+}
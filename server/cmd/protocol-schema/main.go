@@ -0,0 +1,23 @@
+/*
+protocol-schema prints the JSON Schema for the WebSocket protocol, from
+which the client's types are generated:
+
+	go run ./cmd/protocol-schema > protocol/schema.json
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+
+	protocol "server/protocol"
+)
+
+func main() {
+	schema, err := protocol.Schema()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "protocol-schema: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(schema))
+}
@@ -0,0 +1,33 @@
+/*
+trivia-lint checks the trivia directory for content problems and exits
+non-zero if it finds any, so it can gate content changes:
+
+	go run ./cmd/trivia-lint -dir ../trivia
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	trivia "server/trivia"
+)
+
+func main() {
+	dir := flag.String("dir", "../trivia", "trivia directory to check")
+	flag.Parse()
+
+	problems, err := trivia.Lint(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "trivia-lint: %v\n", err)
+		os.Exit(2)
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "trivia-lint: %d problem(s) found\n", len(problems))
+		os.Exit(1)
+	}
+}
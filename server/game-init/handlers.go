@@ -3,19 +3,26 @@ package gameinit
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 
 	game "server/game"
+	protocol "server/protocol"
 	state "server/state"
+	trivia "server/trivia"
 
 	"github.com/gorilla/websocket"
 )
 
+// MaxLingerTime is the longest a host can keep a finished game open.
+const MaxLingerTime = 300
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
 // Create handles POST /create-game: creates a game and returns wss URL.
+// The board comes from the trivia catalog unless the request carries its own answers.
 func CreateHandler(globalState *state.GlobalState, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -30,16 +37,86 @@ func CreateHandler(globalState *state.GlobalState, w http.ResponseWriter, r *htt
 		writeError(w, http.StatusBadRequest, "title required")
 		return
 	}
-	fmt.Println(req)
-	if req.LobbyTime < 10 || req.GameTime < 10 {
+	// a time of 0 falls back to the quiz's default
+	tooShort := func(t int) bool { return t != 0 && t < 10 }
+	if tooShort(req.LobbyTime) || tooShort(req.GameTime) {
 		writeError(w, http.StatusBadRequest, "Must have at least 10s for lobby/game")
 		return
 	}
-	m := globalState.Create(req.Title, req.LobbyTime, req.GameTime)
+	if req.LingerTime != nil && (*req.LingerTime < 0 || *req.LingerTime > MaxLingerTime) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("lingerTime must be between 0 and %ds", MaxLingerTime))
+		return
+	}
+	var strictness game.Strictness
+	if req.Strictness != "" {
+		var err error
+		if strictness, err = game.ParseStrictness(req.Strictness); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	tieBreaker, err := game.ParseTieBreaker(req.TieBreaker)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := game.ValidateTeams(req.Teams); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	mode, err := game.ParseMode(req.Mode)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if mode == game.ModeCoop && len(req.Teams) > 0 {
+		writeError(w, http.StatusBadRequest, "a coop game can't have teams")
+		return
+	}
+	steal := req.Steal != nil
+	stealCooldown, maxSteals := game.DefaultStealCooldown, game.DefaultMaxSteals
+	if steal {
+		if mode == game.ModeCoop {
+			writeError(w, http.StatusBadRequest, "a coop game can't have steal mode")
+			return
+		}
+		if req.Steal.Cooldown != nil {
+			stealCooldown = *req.Steal.Cooldown
+		}
+		if req.Steal.MaxSteals != 0 {
+			maxSteals = req.Steal.MaxSteals
+		}
+		if err := game.ValidateSteal(stealCooldown, maxSteals); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	var answers []game.Answer
+	if req.Answers != nil {
+		if answers, err = trivia.ValidateCustomQuiz(req.Title, req.Answers); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	m := globalState.CreateGame(state.GameOptions{
+		Title:         req.Title,
+		LobbyTime:     req.LobbyTime,
+		GameTime:      req.GameTime,
+		LingerTime:    req.LingerTime,
+		Strictness:    strictness,
+		TieBreaker:    tieBreaker,
+		Teams:         req.Teams,
+		Mode:          mode,
+		Steal:         steal,
+		StealCooldown: stealCooldown,
+		MaxSteals:     maxSteals,
+		Answers:       answers,
+	})
 	if m == nil {
 		writeError(w, http.StatusBadRequest, "Invalid title")
 		return
 	}
+	log.Printf("created game %s: %q", m.Code, m.Title)
 
 	go func() {
 		defer globalState.RemoveGame(m.Code)
@@ -47,7 +124,7 @@ func CreateHandler(globalState *state.GlobalState, w http.ResponseWriter, r *htt
 		m.Run()
 	}()
 
-	writeJSON(w, http.StatusOK, CreateResponse{Code: m.Code})
+	writeJSON(w, http.StatusOK, CreateResponse{Code: m.Code, ResultsURL: buildResultsURL(r, m.Code)})
 }
 
 /*
@@ -72,61 +149,71 @@ func GetWSURLHandler(globalState *state.GlobalState, w http.ResponseWriter, r *h
 	writeJSON(w, http.StatusOK, WSURLResponse{URL: url})
 }
 
-// Connect handles GET /ws: upgrades to WebSocket and adds the player to the game.
+/*
+Connect handles GET /ws: upgrades to WebSocket and adds the player to the game.
+The client picks a protocol version with &v= (see package protocol). The
+welcome message carries a session token; a player whose connection drops
+can reconnect with the same user and &token=, even after the game has started.
+A connection that can't join gets one protocol.Error and is closed.
+*/
 func Connect(globalState *state.GlobalState, w http.ResponseWriter, r *http.Request) {
 	code := r.URL.Query().Get("game")
 	username := r.URL.Query().Get("user")
+	token := r.URL.Query().Get("token")
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
-	if code == "" || username == "" {
-		conn.WriteJSON(map[string]string{
-			"type":    "error",
-			"message": "Need to enter a code and a username.",
-		})
+	reject := func(code protocol.ErrorCode, message string) {
+		conn.WriteJSON(protocol.NewError(code, message))
 		conn.Close()
+	}
+	version, err := protocol.Negotiate(r.URL.Query().Get("v"))
+	if err != nil {
+		reject(protocol.ErrUnsupportedVersion, fmt.Sprintf("Protocol versions %d to %d are supported.", protocol.LegacyVersion, protocol.Version))
+		return
+	}
+	if code == "" || username == "" {
+		reject(protocol.ErrMissingParams, "Need to enter a code and a username.")
 		return
 	}
 	m := globalState.GetGame(code)
 	if m == nil {
-		conn.WriteJSON(map[string]string{
-			"type":    "error",
-			"message": "No game with this code.",
-		})
-		conn.Close()
+		reject(protocol.ErrGameNotFound, "No game with this code.")
 		return
 	}
 
-	m.Lock()
-	defer m.Unlock()
-
-	if m.HasPlayerLocked(username) {
-		conn.WriteJSON(map[string]string{
-			"type":    "error",
-			"message": "Username taken in this lobby.",
-		})
-		conn.Close()
+	player, _, err := m.Join(username, token)
+	if err != nil {
+		reject(joinError(err))
 		return
 	}
 
-	if m.GameStarted {
+	player.Attach(conn, version)
+	// reply before the player's routines start, so nothing else writes to conn yet
+	if version == protocol.LegacyVersion {
 		conn.WriteJSON(map[string]string{
-			"type":    "error",
-			"message": "This game has already started",
+			"type":    "success",
+			"message": m.Title,
+			"token":   player.Token,
 		})
-		conn.Close()
-		return
+	} else {
+		conn.WriteJSON(protocol.Welcome{Type: protocol.TypeWelcome, Version: version, Title: m.Title, Token: player.Token})
 	}
+	player.Start(m)
+	// the board is only sent whole once; after that the client gets Claimed events
+	m.Resync(player)
+}
 
-	color := m.AssignColorLocked()
-	player := game.NewPlayer(username, conn, color, code)
-	// this will start routines for the player
-	m.AddPlayerLocked(username, player)
-	conn.WriteJSON(map[string]string{
-		"type":    "success",
-		"message": m.Title,
-	})
+// joinError is what Connect tells a client that couldn't join.
+func joinError(err error) (protocol.ErrorCode, string) {
+	switch err {
+	case game.ErrUsernameTaken:
+		return protocol.ErrUsernameTaken, "Username taken in this lobby."
+	case game.ErrGameStarted:
+		return protocol.ErrGameStarted, "This game has already started"
+	}
+	return protocol.ErrGameClosed, "This game is over."
 }
 
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
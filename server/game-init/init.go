@@ -17,4 +17,13 @@ func RegisterRoutes(mux *http.ServeMux, globalState *state.GlobalState) {
 	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		Connect(globalState, w, r)
 	})
+	mux.HandleFunc("GET /replay/{id}", func(w http.ResponseWriter, r *http.Request) {
+		Replay(globalState, w, r)
+	})
+	mux.HandleFunc("GET /games/{code}/results", func(w http.ResponseWriter, r *http.Request) {
+		ResultsHandler(globalState, w, r)
+	})
+	mux.HandleFunc("GET /games/{code}/results.csv", func(w http.ResponseWriter, r *http.Request) {
+		ResultsCSVHandler(globalState, w, r)
+	})
 }
@@ -0,0 +1,64 @@
+package gameinit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	game "server/game"
+	"server/history"
+	protocol "server/protocol"
+	"server/state"
+)
+
+/*
+Replay handles GET /replay/{id}: upgrades to WebSocket and plays back the
+recorded match with that ID (see package history) in the events of a live
+game, so a client can render it like one it is playing. &speed= speeds it
+up, from 1 (the default) to game.MaxReplaySpeed; &v= picks the protocol
+version as for /ws. The connection gets the same first message as a player
+joining, then the game, and is closed after the leaderboard.
+*/
+func Replay(globalState *state.GlobalState, w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	reject := func(code protocol.ErrorCode, message string) {
+		conn.WriteJSON(protocol.NewError(code, message))
+	}
+	version, err := protocol.Negotiate(r.URL.Query().Get("v"))
+	if err != nil {
+		reject(protocol.ErrUnsupportedVersion, fmt.Sprintf("Protocol versions %d to %d are supported.", protocol.LegacyVersion, protocol.Version))
+		return
+	}
+	speed := 1.0
+	if s := r.URL.Query().Get("speed"); s != "" {
+		if speed, err = strconv.ParseFloat(s, 64); err != nil || speed < 1 || speed > game.MaxReplaySpeed {
+			reject(protocol.ErrInvalidParams, fmt.Sprintf("speed must be between 1 and %d.", game.MaxReplaySpeed))
+			return
+		}
+	}
+	store := globalState.History()
+	if store == nil {
+		reject(protocol.ErrMatchNotFound, "This server keeps no match history.")
+		return
+	}
+	match, err := store.Get(r.PathValue("id"))
+	if err == history.ErrNotFound {
+		reject(protocol.ErrMatchNotFound, "No recorded match with this ID.")
+		return
+	}
+	if err != nil {
+		reject(protocol.ErrMatchNotFound, "This match can't be read.")
+		return
+	}
+
+	if version == protocol.LegacyVersion {
+		conn.WriteJSON(map[string]string{"type": "success", "message": match.Title})
+	} else {
+		conn.WriteJSON(protocol.Welcome{Type: protocol.TypeWelcome, Version: version, Title: match.Title})
+	}
+	game.StreamReplay(conn, version, match.Results, speed, globalState.Clock())
+}
@@ -0,0 +1,70 @@
+package gameinit
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	game "server/game"
+	"server/state"
+)
+
+// buildResultsURL returns the http:// or https:// URL a game's results are
+// served at. It works from the moment the game is created and keeps naming
+// the same game until the results expire, or with a history store until the
+// code is used by a later game.
+func buildResultsURL(r *http.Request, code string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/games/" + code + "/results"
+}
+
+// lookupResults writes a 404 and returns false if the game in the path has
+// no results to serve.
+func lookupResults(globalState *state.GlobalState, w http.ResponseWriter, r *http.Request) (game.Results, bool) {
+	results, ok := globalState.GetResults(r.PathValue("code"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "no results for this game; it may not have finished, or its results have expired")
+	}
+	return results, ok
+}
+
+/*
+ResultsHandler serves a finished game's results as JSON, at
+GET /games/{code}/results, for as long as the server retains them.
+*/
+func ResultsHandler(globalState *state.GlobalState, w http.ResponseWriter, r *http.Request) {
+	if results, ok := lookupResults(globalState, w, r); ok {
+		writeJSON(w, http.StatusOK, results)
+	}
+}
+
+/*
+ResultsCSVHandler serves the standings from ResultsHandler as CSV, one row
+per player, at GET /games/{code}/results.csv. A player's items are joined
+with "; ". Team games have a team column after the username.
+*/
+func ResultsCSVHandler(globalState *state.GlobalState, w http.ResponseWriter, r *http.Request) {
+	results, ok := lookupResults(globalState, w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+results.Code+`-results.csv"`)
+	out := csv.NewWriter(w)
+	teams := len(results.Teams) > 0
+	row := func(cells ...string) {
+		if !teams {
+			cells = append(cells[:2], cells[3:]...)
+		}
+		out.Write(cells)
+	}
+	row("rank", "username", "team", "correct", "items")
+	for _, e := range results.Standings {
+		row(strconv.Itoa(e.Rank), e.Username, e.Team, strconv.Itoa(e.Count), strings.Join(e.Items, "; "))
+	}
+	out.Flush()
+}
@@ -1,14 +1,52 @@
 package gameinit
 
+import trivia "server/trivia"
+
 // CreateRequest is the JSON body for /create-game.
 type CreateRequest struct {
 	Title     string `json:"title"`
 	LobbyTime int    `json:"lobbyTime"`
 	GameTime  int    `json:"gameTime"`
+	// LingerTime is how long the game stays open after the leaderboard, up to
+	// MaxLingerTime. 0 closes it on the next tick; omitted uses the default.
+	LingerTime *int `json:"lingerTime,omitempty"`
+	// Times of 0 and an empty Strictness use the quiz's defaults.
+	// Strictness is one of "exact", "normal" or "lenient".
+	Strictness string `json:"strictness,omitempty"`
+	// TieBreaker orders players with the same score on the leaderboard:
+	// "shared" (the default) gives them the same rank, "lastClaim" ranks
+	// whoever made their last claim first higher.
+	TieBreaker string `json:"tieBreaker,omitempty"`
+	// Teams, when present, makes this a team game with teams of these
+	// names. Players are balanced across them as they join and can switch
+	// in the lobby; the leaderboard ranks the teams.
+	Teams []string `json:"teams,omitempty"`
+	// Mode is "competitive" (the default) or "coop", in which everyone
+	// works on the board together and wins by finding every square in time.
+	// A coop game can't have teams.
+	Mode string `json:"mode,omitempty"`
+	// Steal, when present, turns on steal mode: guessing a square someone
+	// else claimed takes it from them. A coop game can't have steal mode.
+	Steal *StealOptions `json:"steal,omitempty"`
+	// Answers, when present, is a custom quiz to play instead of the trivia
+	// quiz named Title. Each answer is a string or {"answer", "aliases"};
+	// like in trivia files, unknown answer fields are rejected.
+	Answers []trivia.Answer `json:"answers,omitempty"`
+}
+
+// StealOptions are the rules for steal mode. Omitted fields use the defaults.
+type StealOptions struct {
+	// Cooldown is how many seconds a square is safe after it is taken, up
+	// to game.MaxStealCooldown. 0 lets it be stolen straight back.
+	Cooldown *int `json:"cooldown,omitempty"`
+	// MaxSteals is how many times one square can be stolen, from 1 to
+	// game.MaxStealLimit.
+	MaxSteals int `json:"maxSteals,omitempty"`
 }
 
 type CreateResponse struct {
-	Code string `json:"code"`
+	Code       string `json:"code"`
+	ResultsURL string `json:"resultsUrl"` // where the results can be fetched and shared once the game finishes
 }
 
 // JoinRequest is the JSON body for /join-game.
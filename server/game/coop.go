@@ -0,0 +1,52 @@
+package game
+
+import (
+	"errors"
+	"math"
+)
+
+// A Mode is how players play against each other, or together.
+type Mode string
+
+const (
+	ModeCompetitive Mode = "competitive" // players, or teams, race for squares
+	ModeCoop        Mode = "coop"        // everyone works on one board against the clock
+)
+
+// ParseMode validates s, defaulting to ModeCompetitive when empty.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "":
+		return ModeCompetitive, nil
+	case ModeCompetitive, ModeCoop:
+		return Mode(s), nil
+	}
+	return "", errors.New("mode must be one of competitive, coop")
+}
+
+// CoopResult is how a cooperative game went: it succeeds if every square is
+// found before time runs out.
+type CoopResult struct {
+	Success    bool     `json:"success"`
+	Found      int      `json:"found"`
+	Total      int      `json:"total"`
+	Completion float64  `json:"completion"` // percent of squares found, to one decimal place
+	Missed     []string `json:"missed"`     // squares nobody found, sorted
+}
+
+// coopResult returns how the game went, or nil unless it is cooperative.
+func (m *Manager) coopResult() *CoopResult {
+	if m.opts.Mode != ModeCoop {
+		return nil
+	}
+	r := &CoopResult{
+		Success: m.squaresTaken == len(m.board),
+		Found:   m.squaresTaken,
+		Total:   len(m.board),
+		Missed:  m.unfound(),
+	}
+	if r.Total > 0 {
+		r.Completion = math.Round(1000*float64(r.Found)/float64(r.Total)) / 10
+	}
+	return r
+}
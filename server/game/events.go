@@ -1,24 +1,52 @@
 package game
 
+import protocol "server/protocol"
+
 /*
-An event that we will send back to a player
+An event that we will send back to a player. Clients on the legacy protocol
+get it as-is, plus a Board event with the whole board whenever it changes;
+newer ones get the protocol message it converts to (see wire.go). Fields
+the original client doesn't know are left out when empty.
+
+The board is sent whole in a Snapshot, on joining and on request, and after
+that as one Claimed event per square, and in steal mode one Stolen event per
+steal. Claimed and Stolen events share one numbering, 1, 2, 3... in Seq; a
+client that sees a gap should send ResyncItem for a new Snapshot, whose Seq
+says which event comes next (Seq+1).
 */
 type GameEvent struct {
 	Type        string
-	State       map[string]*Player
+	State       map[string]*Player // set on a Snapshot, and on a legacy Board: every square and who claimed it, nil if nobody has
 	TimeLeft    int
 	Winner      *Player
 	Players     map[string]*Player
-	Leaderboard []LeaderboardEntry
+	Leaderboard []LeaderboardEntry    // set on a Leaderboard, and on a Snapshot once the game has finished: every player, ranked
+	Unfound     []string              `json:",omitempty"` // set with Leaderboard: the squares nobody claimed
+	Started     bool                  `json:",omitempty"` // set on a Snapshot: whether the game is past the lobby
+	Error       string                `json:",omitempty"` // set on an Error: why a request was rejected
+	Code        protocol.ErrorCode    `json:",omitempty"` // set on an Error
+	Phase       Phase                 `json:",omitempty"` // set on a Phase change and a Snapshot
+	Seq         int                   `json:",omitempty"` // set on Claimed, Stolen and Snapshot: how many times the board has changed
+	Item        string                `json:",omitempty"` // set on Claimed and Guess: the square
+	Player      *Player               `json:",omitempty"` // set on Claimed and Stolen: who claimed it; on Guess: who holds, or held, the square
+	From        *Player               `json:",omitempty"` // set on Stolen: who the square was taken from
+	RequestID   string                `json:",omitempty"` // set on Guess: the ID of the request it answers
+	Outcome     protocol.GuessOutcome `json:",omitempty"` // set on Guess
+	Teams       []Team                `json:",omitempty"` // set on a Snapshot in team games
+	TeamBoard   []TeamStanding        `json:",omitempty"` // set with Leaderboard in team games: every team, ranked
+	Coop        *CoopResult           `json:",omitempty"` // set with Leaderboard in cooperative games
 }
 
 /*
 An incoming request from a player.
 The "Item" represents the item that the player
-wants to enter into the board.
+wants to enter into the board. Username and Code
+are optional; the server knows who sent it.
 */
 type PlayerRequest struct {
 	Username string `json:"username"`
 	Code     string `json:"code"`
 	Item     string `json:"Item"`
+	ID       string `json:"id"`   // optional; echoed in the Guess event that answers a claim
+	Team     string `json:"team"` // with TeamItem: the team to move to
 }
@@ -0,0 +1,44 @@
+package game
+
+import (
+	"time"
+
+	protocol "server/protocol"
+)
+
+// DefaultGuessRate is how many claims a player may make in one second of
+// the game clock. Faster guesses are answered with GuessRateLimited.
+const DefaultGuessRate = 10
+
+// guessWindow counts a player's guesses in the second that began at start.
+type guessWindow struct {
+	start time.Time
+	n     int
+}
+
+// isGuess reports whether req is a claim on a square rather than a request
+// like RESYNC, TEAM or GAME_OVER.
+func (req PlayerRequest) isGuess() bool {
+	return req.Item != ResyncItem && req.Item != GameOverItem && req.Item != TeamItem
+}
+
+// allowGuess counts a guess by p and reports whether it is within the game's GuessRate.
+func (m *Manager) allowGuess(p *Player) bool {
+	if m.opts.GuessRate == 0 {
+		return true
+	}
+	now := m.clock.Now().Truncate(time.Second)
+	w := m.guesses[p]
+	if !w.start.Equal(now) {
+		w = guessWindow{start: now}
+	}
+	w.n++
+	m.guesses[p] = w
+	return w.n <= m.opts.GuessRate
+}
+
+// sendGuess tells p what became of their claim req. owner is who holds the
+// square, if the guess matched one.
+func (p *Player) sendGuess(req PlayerRequest, outcome protocol.GuessOutcome, item string, owner *Player) {
+	p.send(GameEvent{Type: "Guess", RequestID: req.ID, Outcome: outcome, Item: item, Player: owner})
+}
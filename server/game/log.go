@@ -0,0 +1,59 @@
+package game
+
+import "time"
+
+// A LogType says what a LogEntry records.
+type LogType string
+
+const (
+	LogJoin  LogType = "join"  // a player joined, or reconnected
+	LogLeave LogType = "leave" // a player left, or dropped
+	LogPhase LogType = "phase" // the game moved to a new phase
+	LogClaim LogType = "claim" // a player claimed a square
+	LogTeam  LogType = "team"  // a player switched teams in the lobby
+	LogSteal LogType = "steal" // a player took a square from another
+)
+
+// A LogEntry is one thing that happened in a game. Results carry the log of
+// a finished game, from which it can be replayed (see StreamReplay).
+type LogEntry struct {
+	Type    LogType `json:"type"`
+	Elapsed float64 `json:"elapsed"`          // seconds since the game started; negative in the lobby
+	Player  string  `json:"player,omitempty"` // username, on joins, leaves, claims and steals
+	Color   string  `json:"color,omitempty"`  // on joins
+	Team    string  `json:"team,omitempty"`   // on joins and team switches, in team games
+	Item    string  `json:"item,omitempty"`   // on claims and steals
+	From    string  `json:"from,omitempty"`   // on steals: who the square was taken from
+	Phase   Phase   `json:"phase,omitempty"`  // on phase changes
+}
+
+// logged is a LogEntry whose Elapsed can't be known until the game starts.
+type logged struct {
+	at    time.Time
+	entry LogEntry
+}
+
+// record adds e to the game's log.
+func (m *Manager) record(e LogEntry) {
+	m.log = append(m.log, logged{at: m.clock.Now(), entry: e})
+}
+
+// recordJoin logs p joining, or coming back.
+func (m *Manager) recordJoin(p *Player) {
+	e := LogEntry{Type: LogJoin, Player: p.Username, Color: p.Color}
+	if t := p.Team(); t != nil {
+		e.Team = t.Name
+	}
+	m.record(e)
+}
+
+// events returns the log with times relative to the start of the game.
+func (m *Manager) events() []LogEntry {
+	events := make([]LogEntry, 0, len(m.log))
+	for _, l := range m.log {
+		e := l.entry
+		e.Elapsed = l.at.Sub(m.startedAt).Seconds()
+		events = append(events, e)
+	}
+	return events
+}
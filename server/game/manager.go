@@ -1,9 +1,12 @@
 package game
 
 import (
-	"sort"
-	"sync"
+	"fmt"
+	"maps"
 	"time"
+
+	clock "server/clock"
+	protocol "server/protocol"
 )
 
 var PlayerColors = []string{
@@ -19,261 +22,402 @@ var PlayerColors = []string{
 	"51 99% 62%",   // #Fee440
 }
 
-type Manager struct {
-	Title           string              // name of the game; key into trivia/*.json
-	Code            string              // unique game code, 6 uppercase letters/numbers
-	Players         map[string]*Player  // maps player usernames to player objects
-	Board           map[string]*Player  // category item -> player who claimed it (nil if unclaimed)
-	Colors          map[string]struct{} // set of assigned colors
-	Correct         map[*Player]int     // maps players to number of correct items they've inputted
-	Time            int                 // seconds remaining (60 until start, then 180)
-	InboundRequests chan PlayerRequest
-	GameStarted     bool
-	SquaresTaken    int
-	LobbyTime       int
-	GameTime        int
-	mu              sync.RWMutex
-}
-
-type LeaderboardEntry struct {
-	Username string `json:"username"`
-	Color    string `json:"color"`
-	Count    int    `json:"correct"`
+// Options are a game's settings. They are fixed once the Manager is created.
+type Options struct {
+	LingerTime int        // seconds a finished game stays open, e.g. for reconnects
+	GuessRate  int        // claims a player may make per second; 0 for no limit
+	TieBreaker TieBreaker // how the leaderboard orders players with the same score
+	Mode       Mode       // whether players compete or cooperate
+	// In steal mode a player can take a square someone else claimed by
+	// guessing it StealCooldown seconds or more after it was last taken,
+	// up to MaxSteals times per square.
+	Steal         bool
+	StealCooldown int
+	MaxSteals     int
+	// OnFinish, if set, is called with the results when the game finishes,
+	// on a goroutine of its own so a slow store doesn't hold up the game.
+	OnFinish func(Results)
 }
 
-// NewManager creates a Manager with the given title and code. Time is set to 60,
-// board and colors are initialized empty.
-func NewManager(title, code string, lobbyTime, gameTime int) *Manager {
-	return &Manager{
-		Title:           title,
-		Code:            code,
-		Players:         make(map[string]*Player),
-		Board:           make(map[string]*Player),
-		Colors:          make(map[string]struct{}),
-		Correct:         make(map[*Player]int),
-		Time:            lobbyTime,
-		GameStarted:     false,
-		InboundRequests: make(chan PlayerRequest, 256),
-		SquaresTaken:    0,
-		LobbyTime:       lobbyTime,
-		GameTime:        gameTime,
+// DefaultOptions returns the settings of a game whose host picked none.
+func DefaultOptions() Options {
+	return Options{
+		LingerTime:    DefaultLingerTime,
+		GuessRate:     DefaultGuessRate,
+		TieBreaker:    TieBreakShared,
+		Mode:          ModeCompetitive,
+		StealCooldown: DefaultStealCooldown,
+		MaxSteals:     DefaultMaxSteals,
 	}
 }
 
-func (m *Manager) SetBoardValue(item string, player *Player) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.Board[item] = player
-
-}
+/*
+A Manager runs one game. Its state belongs to a single goroutine, the loop
+started by NewManager: joins, leaves, claims, host commands and snapshot
+queries are all messages to that loop (see messages.go), so nothing else
+ever touches players, the board or the clock. HTTP handlers read the game
+through Snapshot.
+*/
+type Manager struct {
+	Title     string // name of the game; key into trivia/*.json
+	Code      string // unique game code, 6 uppercase letters/numbers
+	LobbyTime int
+	GameTime  int
+	opts      Options // read-only once NewManager returns
 
-func (m *Manager) GetBoardValue(item string) *Player {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.Board[item]
-}
+	// owned by the loop
+	board        map[string]*Player   // category item -> player who claimed it (nil if unclaimed); keys are fixed once answers are set
+	matcher      Matcher              // resolves guesses to board items
+	players      map[string]*Player   // maps player usernames to player objects
+	colors       map[string]struct{}  // set of assigned colors
+	correct      map[*Player]int      // maps players to number of correct items they've inputted
+	claimedAt    map[string]time.Time // when each claimed square was claimed, or last stolen
+	steals       map[string]int       // how many times each square has been stolen
+	log          []logged             // joins, leaves, phase changes and claims
+	teams        []*Team              // nil unless this is a team game
+	strictness   Strictness
+	startedAt    time.Time
+	guesses      map[*Player]guessWindow // for rate limiting claims
+	time         int                     // seconds remaining (lobby countdown, then game)
+	linger       int                     // seconds left before a finished game closes
+	abandoned    int                     // seconds a started game has had nobody connected
+	phase        Phase
+	squaresTaken int
+	seq          int      // board changes so far: claims, and steals
+	results      *Results // set when the game finishes; read by Results once the loop has exited
+	clock        clock.Clock
+	ticker       clock.Ticker // nil until Run starts the clock
 
-// HasPlayer returns whether the username is already a player in this game.
-func (m *Manager) HasPlayer(username string) bool {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	_, ok := m.Players[username]
-	return ok
+	inbox chan message
+	done  chan struct{} // closes when the loop exits
 }
 
-// AddPlayer adds the player to this game.
-func (m *Manager) AddPlayer(username string, p *Player) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	if p == nil {
-		return
+// NewManager creates a Manager with the given title, code and settings and
+// starts its loop. Players can join right away; the lobby countdown starts
+// with Run. A nil clk means the system clock.
+func NewManager(title, code string, lobbyTime, gameTime int, clk clock.Clock, opts Options) *Manager {
+	if clk == nil {
+		clk = clock.Real
 	}
-	m.Players[username] = p
-	m.Colors[p.Color] = struct{}{}
+	m := &Manager{
+		Title:     title,
+		Code:      code,
+		board:     make(map[string]*Player),
+		LobbyTime: lobbyTime,
+		GameTime:  gameTime,
+		opts:      opts,
+		players:   make(map[string]*Player),
+		colors:    make(map[string]struct{}),
+		correct:   make(map[*Player]int),
+		claimedAt: make(map[string]time.Time),
+		steals:    make(map[string]int),
+		guesses:   make(map[*Player]guessWindow),
+		time:      lobbyTime,
+		phase:     PhaseLobby,
+		clock:     clk,
+		inbox:     make(chan message, 256),
+		done:      make(chan struct{}),
+	}
+	go m.loop()
+	return m
 }
 
-// AssignColor returns a hex color not yet used in this game. Caller should add it when adding the player.
-func (m *Manager) AssignColor() string {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	return m.AssignColorLocked()
+// Options returns the game's settings.
+func (m *Manager) Options() Options {
+	return m.opts
 }
 
-// Lock acquires the Manager's mutex. Use with Unlock to wrap critical sections.
-func (m *Manager) Lock() {
-	m.mu.Lock()
+// Run starts the game clock and blocks until the game closes.
+func (m *Manager) Run() {
+	if m.send(runMsg{}) {
+		<-m.done
+	}
 }
 
-// Unlock releases the Manager's mutex.
-func (m *Manager) Unlock() {
-	m.mu.Unlock()
+// Done returns a channel that closes once the game has closed.
+func (m *Manager) Done() <-chan struct{} {
+	return m.done
 }
 
-// HasPlayerLocked returns whether the username is already a player. Caller must hold lock via Lock/Unlock.
-func (m *Manager) HasPlayerLocked(username string) bool {
-	_, ok := m.Players[username]
-	return ok
+func (m *Manager) loop() {
+	defer close(m.done)
+	for m.phase != PhaseClosed {
+		var tick <-chan time.Time
+		if m.ticker != nil {
+			tick = m.ticker.C()
+		}
+		select {
+		case <-tick:
+			m.tick()
+		case msg := <-m.inbox:
+			msg.handle(m)
+		}
+	}
+	if m.ticker != nil {
+		m.ticker.Stop()
+	}
 }
 
-// AssignColorLocked returns a hex color not yet used. Caller must hold lock.
-func (m *Manager) AssignColorLocked() string {
-	for _, c := range PlayerColors {
-		if _, used := m.Colors[c]; !used {
-			return c
+func (m *Manager) tick() {
+	if m.phase.Started() && m.connectedPlayers() == 0 {
+		if m.abandoned++; m.abandoned >= AbandonTime {
+			m.close()
+			return
+		}
+	}
+	switch m.phase {
+	case PhaseLobby, PhaseCountdown:
+		if len(m.players) == 0 {
+			// don't tick until someone has joined
+			return
+		}
+		if m.phase == PhaseLobby {
+			m.setPhase(PhaseCountdown)
+		}
+		m.time--
+		if m.time <= 0 {
+			m.startGame()
+		}
+		m.broadcastTime()
+		if !m.phase.Started() {
+			m.broadcastPlayers()
+		}
+	case PhaseRunning:
+		m.time--
+		if m.time <= 0 {
+			m.finish()
+		}
+		m.broadcastTime()
+	case PhaseFinished:
+		m.linger--
+		if m.linger <= 0 {
+			m.close()
 		}
 	}
-	return "#888888"
 }
 
-// AddPlayerLocked adds the player. Caller must hold lock.
-func (m *Manager) AddPlayerLocked(username string, p *Player) {
-	if p == nil {
-		return
+// startGame ends the lobby and starts the game clock from GameTime.
+func (m *Manager) startGame() error {
+	if err := m.setPhase(PhaseRunning); err != nil {
+		return err
 	}
-	m.Players[username] = p
-	m.Colors[p.Color] = struct{}{}
-	go p.Read(m)
-	go p.Write()
+	m.time = m.GameTime
+	m.startedAt = m.clock.Now()
+	if m.ticker != nil {
+		m.ticker.Reset(1 * time.Second)
+	}
+	for _, p := range m.players {
+		m.correct[p] = 0
+	}
+	m.broadcastStartGame()
+	return nil
 }
 
-func (m *Manager) Run() {
-	timer := time.NewTicker(1 * time.Second)
-	for {
-		select {
-		case <-timer.C:
-			if len(m.Players) == 0 {
-				// don't tick until someone has joined
-				continue
-			}
-			m.Time--
-			if m.Time < -10 { // arbitrary threshold to end game
-				m.CloseConnections()
-				return
-			}
-			if m.Time == 0 {
-				if !m.GameStarted {
-					if len(m.Players) == 0 {
-						return
-					}
-					m.Time = m.GameTime
-					timer.Stop()
-					timer = time.NewTicker(1 * time.Second)
-					m.GameStarted = true
-					for _, p := range m.Players {
-						m.Correct[p] = 0
-					}
-					m.BroadcastStartGame()
-
-				} else {
-					// TODO: Need to send a winner here
-					m.BroadcastWinner()
-				}
-
-			}
-			m.BroadcastTime()
-			m.BroadcastState()
-			if !m.GameStarted {
-				m.BroadcastPlayers()
-			}
-
-		case event, ok := <-m.InboundRequests:
-			if event.Item == "GAME_OVER" && m.Time <= 0 {
-				m.CloseConnections()
-				return
-			}
-
-			if !m.GameStarted || m.Time < 0 {
+// finish stops play and sends the leaderboard. The game closes after LingerTime.
+func (m *Manager) finish() error {
+	if err := m.setPhase(PhaseFinished); err != nil {
+		return err
+	}
+	m.time = 0
+	m.linger = m.opts.LingerTime
+	standings := m.leaderboard()
+	settings := Settings{
+		LobbyTime:  m.LobbyTime,
+		GameTime:   m.GameTime,
+		Strictness: m.strictness,
+		TieBreaker: m.opts.TieBreaker,
+		Mode:       m.opts.Mode,
+	}
+	if m.opts.Steal {
+		settings.Steal, settings.StealCooldown, settings.MaxSteals = true, m.opts.StealCooldown, m.opts.MaxSteals
+	}
+	m.results = &Results{
+		Title:      m.Title,
+		Code:       m.Code,
+		Settings:   settings,
+		StartedAt:  m.startedAt,
+		FinishedAt: m.clock.Now(),
+		Standings:  standings,
+		Teams:      m.teamLeaderboard(standings),
+		Coop:       m.coopResult(),
+		Unfound:    m.unfound(),
+		Events:     m.events(),
+	}
+	m.broadcastWinner()
+	if m.opts.OnFinish != nil {
+		go m.opts.OnFinish(*m.results)
+	}
+	return nil
+}
 
-				continue
-			}
-			if !ok || event.Code != m.Code {
-				m.CloseConnections()
-				return
-			}
-			player, playerExists := m.Players[event.Username]
-			if !playerExists {
-				continue
-			}
-			item := event.Item
+// close disconnects everyone and stops the loop.
+func (m *Manager) close() {
+	m.setPhase(PhaseClosed)
+	m.closeConnections()
+}
 
-			currPlayer, itemExists := m.Board[item]
-			if !itemExists || currPlayer != nil {
-				continue
-			}
-			m.Board[item] = player
-			m.Correct[player] += 1
-			m.SquaresTaken += 1
-			if m.SquaresTaken == len(m.Board) {
-				m.BroadcastWinner()
-				m.Time = 0
-			}
+// matchItem returns the board item a guess refers to. Without a Matcher only
+// exact board keys are accepted.
+func (m *Manager) matchItem(guess string) (string, bool) {
+	if m.matcher == nil {
+		_, ok := m.board[guess]
+		return guess, ok
+	}
+	return m.matcher.Match(guess)
+}
 
-			m.BroadcastState()
+func (m *Manager) claim(event PlayerRequest) {
+	player, playerExists := m.players[event.Username]
+	if !playerExists {
+		return
+	}
+	if !m.phase.Accepts(event) {
+		if event.isGuess() {
+			player.sendGuess(event, protocol.GuessNotRunning, "", nil)
+			return
 		}
+		player.SendError(protocol.ErrNotAccepted, fmt.Sprintf("%q is not accepted while the game is %s", event.Item, m.phase))
+		return
+	}
+	if event.Item == ResyncItem {
+		m.sendSnapshot(player)
+		return
+	}
+	if event.Item == TeamItem {
+		m.pickTeam(player, event)
+		return
+	}
+	if event.Item == GameOverItem {
+		// this player is done; the game itself stays open until it stops lingering
+		if conn, _ := player.connection(); conn != nil {
+			conn.Close()
+		}
+		return
+	}
+	if !m.allowGuess(player) {
+		player.sendGuess(event, protocol.GuessRateLimited, "", nil)
+		return
+	}
+	item, matched := m.matchItem(event.Item)
+	currPlayer, itemExists := m.board[item]
+	if !matched || !itemExists {
+		player.sendGuess(event, protocol.GuessNotOnBoard, "", nil)
+		return
+	}
+	if currPlayer != nil {
+		outcome := m.stealOutcome(player, currPlayer, item)
+		if outcome == protocol.GuessStolen {
+			m.steal(player, currPlayer, item)
+		}
+		player.sendGuess(event, outcome, item, currPlayer)
+		return
+	}
+	m.board[item] = player
+	m.claimedAt[item] = m.clock.Now()
+	m.record(LogEntry{Type: LogClaim, Player: player.Username, Item: item})
+	m.correct[player] += 1
+	m.squaresTaken += 1
+	m.seq += 1
+	m.broadcast(GameEvent{Type: "Claimed", Seq: m.seq, Item: item, Player: player})
+	player.sendGuess(event, protocol.GuessClaimed, item, player)
+	if m.squaresTaken == len(m.board) {
+		m.finish()
 	}
 }
 
-func (m *Manager) BroadcastState() {
-	for _, p := range m.Players {
-		select {
-		case p.OutboundRequests <- GameEvent{Type: "Board", State: m.Board}:
-		default:
+// assignColor returns a hex color not yet used in this game.
+func (m *Manager) assignColor() string {
+	for _, c := range PlayerColors {
+		if _, used := m.colors[c]; !used {
+			return c
 		}
 	}
+	return "#888888"
 }
 
-func (m *Manager) BroadcastTime() {
-	for _, p := range m.Players {
-		select {
-		case p.OutboundRequests <- GameEvent{Type: "Time", TimeLeft: m.Time}:
-		default:
-		}
+func (m *Manager) addPlayer(p *Player) {
+	m.players[p.Username] = p
+	m.colors[p.Color] = struct{}{}
+	if len(m.teams) > 0 {
+		p.team.Store(m.smallestTeam())
 	}
+	m.recordJoin(p)
 }
 
-func (m *Manager) BroadcastStartGame() {
-	for _, p := range m.Players {
-		select {
-		case p.OutboundRequests <- GameEvent{Type: "Start"}:
-		default:
+func (m *Manager) connectedPlayers() int {
+	n := 0
+	for _, p := range m.players {
+		if p.Connected() {
+			n++
 		}
 	}
+	return n
 }
 
-func (m *Manager) BroadcastPlayers() {
-	for _, p := range m.Players {
-		select {
-		case p.OutboundRequests <- GameEvent{Type: "Players", Players: m.Players}:
-		default:
+func (m *Manager) removePlayer(p *Player) {
+	delete(m.players, p.Username)
+	delete(m.colors, p.Color)
+	delete(m.correct, p)
+	delete(m.guesses, p)
+}
+
+// broadcast skips disconnected players, who get a snapshot when they return.
+// Events carry copies of the board and players, since Write serializes them
+// on another goroutine.
+func (m *Manager) broadcast(event GameEvent) {
+	for _, p := range m.players {
+		if p.Connected() {
+			p.send(event)
 		}
 	}
 }
 
-func (m *Manager) BroadcastWinner() {
-	lst := make([]LeaderboardEntry, 0, len(m.Correct))
-	for k, v := range m.Correct {
-		lst = append(lst, LeaderboardEntry{Username: k.Username, Color: k.Color, Count: v})
-	}
-	sort.Slice(lst, func(i, j int) bool {
-		return lst[i].Count > lst[j].Count
-	})
-	if len(lst) > 3 {
-		lst = lst[:3]
-	}
+func (m *Manager) broadcastTime() {
+	m.broadcast(GameEvent{Type: "Time", TimeLeft: m.time})
+}
 
-	for _, p := range m.Players {
-		select {
-		case p.OutboundRequests <- GameEvent{Type: "Leaderboard", Leaderboard: lst}:
-		default:
-		}
+func (m *Manager) broadcastStartGame() {
+	m.broadcast(GameEvent{Type: "Start"})
+}
+
+func (m *Manager) broadcastPlayers() {
+	m.broadcast(GameEvent{Type: "Players", Players: maps.Clone(m.players)})
+}
+
+func (m *Manager) broadcastWinner() {
+	m.broadcast(GameEvent{Type: "Leaderboard", Leaderboard: m.results.Standings, Unfound: m.results.Unfound, TeamBoard: m.results.Teams, Coop: m.results.Coop})
+}
+
+// sendSnapshot sends one player the whole game state, so a client that is
+// joining, reconnecting or has missed a Claimed event can redraw. Once the
+// game has finished it carries the final standings, which a player cut off for
+// being slow would otherwise never see.
+func (m *Manager) sendSnapshot(p *Player) {
+	event := GameEvent{
+		Type:     "Snapshot",
+		State:    maps.Clone(m.board),
+		Seq:      m.seq,
+		TimeLeft: m.time,
+		Players:  maps.Clone(m.players),
+		Started:  m.phase.Started(),
+		Phase:    m.phase,
+		Teams:    m.teamsCopy(),
+	}
+	if m.phase == PhaseFinished {
+		event.Leaderboard = m.results.Standings
+		event.Unfound = m.results.Unfound
+		event.TeamBoard = m.results.Teams
+		event.Coop = m.results.Coop
 	}
+	p.send(event)
 }
 
-func (m *Manager) CloseConnections() {
-	for _, p := range m.Players {
-		if p.Connection == nil {
+func (m *Manager) closeConnections() {
+	for _, p := range m.players {
+		conn, _ := p.connection()
+		if conn == nil {
 			continue
 		}
-		p.Connection.Close()
+		conn.Close()
 	}
 }
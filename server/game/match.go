@@ -0,0 +1,252 @@
+package game
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Strictness controls how forgiving answer matching is for a quiz.
+type Strictness string
+
+const (
+	StrictnessExact   Strictness = "exact"   // guess must equal the item or an alias exactly
+	StrictnessNormal  Strictness = "normal"  // case, spacing, punctuation and accents are ignored
+	StrictnessLenient Strictness = "lenient" // normal, plus small typos are tolerated
+)
+
+// ParseStrictness validates s, defaulting to StrictnessNormal when empty.
+func ParseStrictness(s string) (Strictness, error) {
+	switch Strictness(s) {
+	case "":
+		return StrictnessNormal, nil
+	case StrictnessExact, StrictnessNormal, StrictnessLenient:
+		return Strictness(s), nil
+	}
+	return "", errors.New("strictness must be one of exact, normal, lenient")
+}
+
+/*
+An Answer is a single square on the board. Item is the canonical
+name that is stored in the board and broadcast to players; any of
+the Aliases will also claim it. Answers are decoded from JSON as
+trivia.Answer and converted with Quiz.GameAnswers.
+*/
+type Answer struct {
+	Item    string
+	Aliases []string
+}
+
+// Matcher resolves a player's guess to the canonical board item it refers to.
+type Matcher interface {
+	Match(guess string) (item string, ok bool)
+}
+
+// answerMatcher is the default Matcher built from a quiz's answers.
+type answerMatcher struct {
+	strictness Strictness
+	exact      map[string]string // item or alias, verbatim -> item
+	normalized map[string]string // normalized item or alias -> item ("" if ambiguous)
+	keys       []string          // keys of normalized, for typo tolerance
+}
+
+// NewMatcher returns a Matcher over the given answers using strictness s.
+func NewMatcher(answers []Answer, s Strictness) Matcher {
+	am := &answerMatcher{
+		strictness: s,
+		exact:      make(map[string]string),
+		normalized: make(map[string]string),
+	}
+	for _, a := range answers {
+		for _, name := range append([]string{a.Item}, a.Aliases...) {
+			if _, ok := am.exact[name]; !ok {
+				am.exact[name] = a.Item
+			}
+			key := Normalize(name)
+			if key == "" {
+				continue
+			}
+			prev, seen := am.normalized[key]
+			switch {
+			case !seen:
+				am.normalized[key] = a.Item
+				am.keys = append(am.keys, key)
+			case prev != a.Item:
+				// two answers share a spelling; refuse to guess between them
+				am.normalized[key] = ""
+			}
+		}
+	}
+	return am
+}
+
+func (am *answerMatcher) Match(guess string) (string, bool) {
+	if item, ok := am.exact[guess]; ok {
+		return item, true
+	}
+	if am.strictness == StrictnessExact {
+		return "", false
+	}
+	key := Normalize(guess)
+	if key == "" {
+		return "", false
+	}
+	if item, ok := am.normalized[key]; ok {
+		return item, item != ""
+	}
+	if am.strictness != StrictnessLenient {
+		return "", false
+	}
+	return am.closest(key)
+}
+
+// closest returns the single item within typo tolerance of key. Ties between
+// different items are treated as no match.
+func (am *answerMatcher) closest(key string) (string, bool) {
+	limit := typoLimit(key)
+	if limit == 0 {
+		return "", false
+	}
+	best, bestDist := "", limit+1
+	for _, k := range am.keys {
+		d := editDistance(key, k, limit)
+		item := am.normalized[k]
+		switch {
+		case d < bestDist:
+			best, bestDist = item, d
+		case d == bestDist && item != best:
+			best = ""
+		}
+	}
+	return best, best != "" && bestDist <= limit
+}
+
+// typoLimit is the number of edits tolerated for a guess of this length.
+// Short answers must be spelled correctly, or "Nets" would claim "Jets".
+func typoLimit(key string) int {
+	n := len([]rune(key))
+	switch {
+	case n <= 4:
+		return 0
+	case n <= 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the optimal string alignment distance between a and b
+// (Levenshtein plus adjacent transpositions, so "pheonix" is one edit from
+// "phoenix"), or limit+1 once it is certain to exceed limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > limit {
+		return limit + 1
+	}
+	prevPrev := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prevPrev[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prevPrev, prev, curr = prev, curr, prevPrev
+	}
+	return prev[len(rb)]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+/*
+Normalize lowercases s, folds accented Latin letters to ASCII, turns
+"&" into "and", drops apostrophes and treats any other punctuation as
+a word break, then collapses whitespace. "  St. Paul " and "st paul"
+both normalize to "st paul"; "Zürich" becomes "zurich".
+*/
+func Normalize(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		if folded, ok := foldTable[r]; ok {
+			b.WriteString(folded)
+			space = false
+			continue
+		}
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			space = false
+		case r == '\'' || r == '’' || unicode.Is(unicode.Mn, r):
+			// apostrophes and stray combining marks join the surrounding word
+		case r == '&':
+			if !space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString("and ")
+			space = true
+		default:
+			if !space && b.Len() > 0 {
+				b.WriteByte(' ')
+				space = true
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// foldTable maps lowercase accented Latin letters to their ASCII spelling.
+var foldTable = func() map[rune]string {
+	groups := map[string]string{
+		"a":  "àáâãäåāăą",
+		"c":  "çćĉċč",
+		"d":  "ďđð",
+		"e":  "èéêëēĕėęě",
+		"g":  "ĝğġģ",
+		"h":  "ĥħ",
+		"i":  "ìíîïĩīĭįı",
+		"j":  "ĵ",
+		"k":  "ķ",
+		"l":  "ĺļľŀł",
+		"n":  "ñńņňŉ",
+		"o":  "òóôõöøōŏő",
+		"r":  "ŕŗř",
+		"s":  "śŝşšș",
+		"t":  "ţťŧț",
+		"u":  "ùúûüũūŭůűų",
+		"w":  "ŵ",
+		"y":  "ýÿŷ",
+		"z":  "źżž",
+		"ae": "æ",
+		"oe": "œ",
+		"ss": "ß",
+		"th": "þ",
+	}
+	table := make(map[rune]string)
+	for ascii, accented := range groups {
+		for _, r := range accented {
+			table[r] = ascii
+		}
+	}
+	return table
+}()
@@ -0,0 +1,389 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	ErrGameClosed     = errors.New("this game is over")
+	ErrUsernameTaken  = errors.New("username taken in this lobby")
+	ErrGameStarted    = errors.New("this game has already started")
+	ErrInvalidCommand = errors.New("unknown command")
+)
+
+// A Command is a host action on the game.
+type Command string
+
+const (
+	CommandStart  Command = "start"  // end the lobby countdown early
+	CommandPause  Command = "pause"  // stop the game clock and claims
+	CommandResume Command = "resume" // restart a paused game
+	CommandEnd    Command = "end"    // end the game now and show the leaderboard
+)
+
+// Snapshot is a read-only copy of a game's state.
+type Snapshot struct {
+	Title    string            `json:"title"`
+	Code     string            `json:"code"`
+	Phase    Phase             `json:"phase"`
+	Started  bool              `json:"started"`
+	TimeLeft int               `json:"timeLeft"`
+	Players  []PlayerSnapshot  `json:"players"` // sorted by username
+	Board    map[string]string `json:"board"`   // item -> username of who claimed it, "" if unclaimed
+	// In team games, the teams and the team each claimed square was claimed for.
+	Teams      []Team            `json:"teams,omitempty"`
+	BoardTeams map[string]string `json:"boardTeams,omitempty"`
+}
+
+type PlayerSnapshot struct {
+	Username  string `json:"username"`
+	Color     string `json:"color"`
+	Correct   int    `json:"correct"`
+	Connected bool   `json:"connected"`
+	Team      string `json:"team,omitempty"`
+}
+
+// message is anything the loop processes. handle runs on the loop goroutine.
+type message interface {
+	handle(m *Manager)
+}
+
+// send queues msg for the loop. It returns false if the game has closed.
+func (m *Manager) send(msg message) bool {
+	select {
+	case m.inbox <- msg:
+		return true
+	case <-m.done:
+		return false
+	}
+}
+
+/*
+ask sends msg to the loop and waits for the loop to answer on reply. ok is
+false if the game has closed, including when msg was queued just as the loop
+exited and so will never be handled.
+*/
+func ask[T any](m *Manager, msg message, reply <-chan T) (r T, ok bool) {
+	if !m.send(msg) {
+		return r, false
+	}
+	select {
+	case r = <-reply:
+		return r, true
+	case <-m.done:
+		return r, false
+	}
+}
+
+type runMsg struct{}
+
+func (runMsg) handle(m *Manager) {
+	if m.ticker == nil {
+		m.ticker = m.clock.NewTicker(1 * time.Second)
+	}
+}
+
+type answersMsg struct {
+	answers    []Answer
+	strictness Strictness
+	reply      chan struct{}
+}
+
+func (msg answersMsg) handle(m *Manager) {
+	for _, a := range msg.answers {
+		m.board[a.Item] = nil
+	}
+	m.matcher = NewMatcher(msg.answers, msg.strictness)
+	m.strictness = msg.strictness
+	close(msg.reply)
+}
+
+// SetAnswers fills the board with one unclaimed square per answer and builds
+// the Matcher that resolves guesses against them. Call it before anyone joins.
+func (m *Manager) SetAnswers(answers []Answer, s Strictness) {
+	reply := make(chan struct{})
+	ask(m, answersMsg{answers: answers, strictness: s, reply: reply}, reply)
+}
+
+type joinMsg struct {
+	username string
+	token    string
+	reply    chan joinReply
+}
+
+type joinReply struct {
+	player *Player
+	rejoin bool
+	err    error
+}
+
+func (msg joinMsg) handle(m *Manager) {
+	if existing := m.players[msg.username]; existing != nil {
+		if existing.HasToken(msg.token) {
+			existing.disconnected.Store(false)
+			m.abandoned = 0
+			m.recordJoin(existing)
+			m.broadcastPlayers()
+			msg.reply <- joinReply{player: existing, rejoin: true}
+		} else {
+			msg.reply <- joinReply{err: ErrUsernameTaken}
+		}
+		return
+	}
+	if m.phase.Started() {
+		msg.reply <- joinReply{err: ErrGameStarted}
+		return
+	}
+	p := NewPlayer(msg.username, nil, m.assignColor(), m.Code)
+	m.addPlayer(p)
+	msg.reply <- joinReply{player: p}
+}
+
+/*
+Join adds username to the game, or, if token is that player's session token,
+returns the existing player so a dropped connection can be replaced. rejoin
+reports which happened. The player has no connection yet: attach one with
+Attach, then Start its routines.
+*/
+func (m *Manager) Join(username, token string) (p *Player, rejoin bool, err error) {
+	reply := make(chan joinReply, 1)
+	r, ok := ask(m, joinMsg{username: username, token: token, reply: reply}, reply)
+	if !ok {
+		return nil, false, ErrGameClosed
+	}
+	return r.player, r.rejoin, r.err
+}
+
+type leaveMsg struct {
+	player *Player
+	conn   *websocket.Conn // the connection that closed
+}
+
+/*
+A player who leaves the lobby is removed and their color freed. One who
+drops mid-game is marked disconnected and keeps their squares, so they can
+reconnect. A game everyone has left is torn down: at once if it hasn't
+started or is over, otherwise after AbandonTime.
+*/
+func (msg leaveMsg) handle(m *Manager) {
+	if current, _ := msg.player.connection(); current != msg.conn {
+		return // the player has already reconnected
+	}
+	if m.players[msg.player.Username] != msg.player {
+		return
+	}
+	if m.phase.Started() {
+		msg.player.disconnected.Store(true)
+	} else {
+		m.removePlayer(msg.player)
+	}
+	m.record(LogEntry{Type: LogLeave, Player: msg.player.Username})
+	m.broadcastPlayers()
+	if m.connectedPlayers() == 0 && (!m.phase.Started() || m.phase == PhaseFinished) {
+		m.close()
+	}
+}
+
+type claimMsg struct {
+	req PlayerRequest
+}
+
+func (msg claimMsg) handle(m *Manager) {
+	m.claim(msg.req)
+}
+
+// Claim submits a player's guess. It never blocks: if the game is backed up
+// the guess is dropped and Claim returns false.
+func (m *Manager) Claim(req PlayerRequest) bool {
+	select {
+	case m.inbox <- claimMsg{req: req}:
+		return true
+	default: // don't block the channel
+		return false
+	}
+}
+
+type resultsMsg struct {
+	reply chan *Results
+}
+
+func (msg resultsMsg) handle(m *Manager) {
+	msg.reply <- m.results
+}
+
+/*
+Results returns the game's final standings. ok is false until the game has
+finished, and for a game that closed without finishing, e.g. because
+everyone left. Unlike the other queries it still answers once the game has
+closed.
+*/
+func (m *Manager) Results() (results Results, ok bool) {
+	reply := make(chan *Results, 1)
+	r, ok := ask(m, resultsMsg{reply: reply}, reply)
+	if !ok {
+		r = m.results // the loop has exited and won't write it again
+	}
+	if r == nil {
+		return Results{}, false
+	}
+	return *r, true
+}
+
+type commandMsg struct {
+	cmd   Command
+	reply chan error
+}
+
+func (msg commandMsg) handle(m *Manager) {
+	var err error
+	switch msg.cmd {
+	case CommandStart:
+		if err = m.startGame(); err == nil {
+			m.broadcastTime()
+		}
+	case CommandPause:
+		err = m.setPhase(PhasePaused)
+	case CommandResume:
+		if m.phase != PhasePaused {
+			err = fmt.Errorf("%w: %s to %s", ErrInvalidTransition, m.phase, PhaseRunning)
+		} else if err = m.setPhase(PhaseRunning); err == nil && m.ticker != nil {
+			m.ticker.Reset(1 * time.Second)
+		}
+	case CommandEnd:
+		if err = m.finish(); err == nil {
+			m.broadcastTime()
+		}
+	default:
+		err = fmt.Errorf("%w: %q", ErrInvalidCommand, msg.cmd)
+	}
+	msg.reply <- err
+}
+
+// Command runs a host command. It fails with ErrInvalidTransition if the
+// game is in the wrong phase for it.
+func (m *Manager) Command(cmd Command) error {
+	reply := make(chan error, 1)
+	err, ok := ask(m, commandMsg{cmd: cmd, reply: reply}, reply)
+	if !ok {
+		return ErrGameClosed
+	}
+	return err
+}
+
+type snapshotMsg struct {
+	reply chan Snapshot
+}
+
+func (msg snapshotMsg) handle(m *Manager) {
+	s := Snapshot{
+		Title:    m.Title,
+		Code:     m.Code,
+		Phase:    m.phase,
+		Started:  m.phase.Started(),
+		TimeLeft: m.time,
+		Players:  make([]PlayerSnapshot, 0, len(m.players)),
+		Board:    make(map[string]string, len(m.board)),
+	}
+	for _, p := range m.players {
+		s.Players = append(s.Players, PlayerSnapshot{Username: p.Username, Color: p.Color, Correct: m.correct[p], Connected: p.Connected(), Team: teamName(p)})
+	}
+	sort.Slice(s.Players, func(i, j int) bool { return s.Players[i].Username < s.Players[j].Username })
+	if s.Teams = m.teamsCopy(); s.Teams != nil {
+		s.BoardTeams = make(map[string]string)
+	}
+	for item, p := range m.board {
+		s.Board[item] = ""
+		if p != nil {
+			s.Board[item] = p.Username
+			if s.BoardTeams != nil {
+				s.BoardTeams[item] = teamName(p)
+			}
+		}
+	}
+	msg.reply <- s
+}
+
+// Snapshot returns a copy of the game's state. ok is false once the game has closed.
+func (m *Manager) Snapshot() (s Snapshot, ok bool) {
+	reply := make(chan Snapshot, 1)
+	return ask(m, snapshotMsg{reply: reply}, reply)
+}
+
+type playerMsg struct {
+	username string
+	reply    chan *Player
+}
+
+func (msg playerMsg) handle(m *Manager) {
+	msg.reply <- m.players[msg.username]
+}
+
+// Player returns the player with username, or nil.
+func (m *Manager) Player(username string) *Player {
+	reply := make(chan *Player, 1)
+	p, _ := ask(m, playerMsg{username: username, reply: reply}, reply)
+	return p
+}
+
+// HasPlayer returns whether the username is already a player in this game.
+func (m *Manager) HasPlayer(username string) bool {
+	return m.Player(username) != nil
+}
+
+type addPlayerMsg struct {
+	username string
+	player   *Player
+	reply    chan struct{}
+}
+
+func (msg addPlayerMsg) handle(m *Manager) {
+	m.players[msg.username] = msg.player
+	m.colors[msg.player.Color] = struct{}{}
+	m.recordJoin(msg.player)
+	close(msg.reply)
+}
+
+// AddPlayer adds the player to this game without starting its routines.
+func (m *Manager) AddPlayer(username string, p *Player) {
+	if p == nil {
+		return
+	}
+	reply := make(chan struct{})
+	ask(m, addPlayerMsg{username: username, player: p, reply: reply}, reply)
+}
+
+type colorMsg struct {
+	reply chan string
+}
+
+func (msg colorMsg) handle(m *Manager) {
+	msg.reply <- m.assignColor()
+}
+
+// AssignColor returns a hex color not yet used in this game. Caller should add it when adding the player.
+func (m *Manager) AssignColor() string {
+	reply := make(chan string, 1)
+	color, ok := ask(m, colorMsg{reply: reply}, reply)
+	if !ok {
+		return "#888888"
+	}
+	return color
+}
+
+type resyncMsg struct {
+	player *Player
+}
+
+func (msg resyncMsg) handle(m *Manager) {
+	m.sendSnapshot(msg.player)
+}
+
+// Resync queues a full snapshot of the game for one player.
+func (m *Manager) Resync(p *Player) {
+	m.send(resyncMsg{player: p})
+}
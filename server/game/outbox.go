@@ -0,0 +1,117 @@
+package game
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxPending is how many unsent events a player may have before they're
+	// treated as too slow and disconnected. They can reconnect for a snapshot.
+	// Claimed events aren't coalesced, so this leaves room for a large board
+	// being cleared in a burst.
+	MaxPending = 256
+
+	// WriteTimeout is how long one write to a player's connection may block.
+	WriteTimeout = 10 * time.Second
+)
+
+// Delivery metrics, served by MetricsHandler.
+var (
+	eventsSent      = expvar.NewInt("game.events_sent")
+	eventsCoalesced = expvar.NewInt("game.events_coalesced") // replaced by a newer event of the same type before being sent
+	eventsDropped   = expvar.NewInt("game.events_dropped")   // discarded when a slow player was disconnected
+	slowDisconnects = expvar.NewInt("game.slow_disconnects")
+)
+
+/*
+MetricsHandler serves the game.* expvars as a JSON object. Unlike
+expvar.Handler it leaves out the process-wide vars, such as cmdline and
+memstats, so it is safe to expose publicly.
+*/
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, "{")
+		first := true
+		expvar.Do(func(kv expvar.KeyValue) {
+			if !strings.HasPrefix(kv.Key, "game.") {
+				return
+			}
+			if !first {
+				fmt.Fprint(w, ",")
+			}
+			first = false
+			fmt.Fprintf(w, "%q:%s", kv.Key, kv.Value)
+		})
+		fmt.Fprint(w, "}")
+	})
+}
+
+// coalesces reports whether an unsent event of this type is made redundant by
+// a newer one. Everything else, like Start, Phase, Claimed and Leaderboard,
+// is always delivered.
+func coalesces(eventType string) bool {
+	switch eventType {
+	case "Time", "Players":
+		return true
+	}
+	return false
+}
+
+/*
+An outbox queues a player's events for Write. Pushing never blocks the
+Manager: a Time or Players event replaces any older one of its type
+that hasn't been sent yet, and any other event is kept until sent. If the
+queue still grows past MaxPending, push reports overflow and the player is
+disconnected.
+*/
+type outbox struct {
+	mu     sync.Mutex
+	events []GameEvent
+	ready  chan struct{} // has a value while events is non-empty
+}
+
+func newOutbox() *outbox {
+	return &outbox{ready: make(chan struct{}, 1)}
+}
+
+// push queues e and returns false if the queue has overflowed, in which case
+// it has been emptied.
+func (o *outbox) push(e GameEvent) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if coalesces(e.Type) {
+		for i, queued := range o.events {
+			if queued.Type == e.Type {
+				o.events = append(o.events[:i], o.events[i+1:]...)
+				eventsCoalesced.Add(1)
+				break
+			}
+		}
+	}
+	o.events = append(o.events, e)
+	if len(o.events) > MaxPending {
+		eventsDropped.Add(int64(len(o.events)))
+		o.events = nil
+		return false
+	}
+	select {
+	case o.ready <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// take removes and returns everything queued.
+func (o *outbox) take() []GameEvent {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	events := o.events
+	o.events = nil
+	return events
+}
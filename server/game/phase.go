@@ -0,0 +1,89 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A Phase is where a game is in its lifecycle.
+type Phase string
+
+const (
+	PhaseLobby     Phase = "lobby"     // waiting for the first player
+	PhaseCountdown Phase = "countdown" // players are in and the lobby clock is running
+	PhaseRunning   Phase = "running"   // squares can be claimed
+	PhasePaused    Phase = "paused"    // the host stopped the clock
+	PhaseFinished  Phase = "finished"  // the leaderboard is out; the game lingers for LingerTime
+	PhaseClosed    Phase = "closed"    // connections are closed and the Manager has stopped
+)
+
+// GameOverItem is the request a client sends once it has shown the leaderboard.
+const GameOverItem = "GAME_OVER"
+
+// ResyncItem is the request for a fresh Snapshot, accepted in every phase.
+const ResyncItem = "RESYNC"
+
+// DefaultLingerTime is how many seconds a finished game stays open.
+const DefaultLingerTime = 10
+
+// AbandonTime is how many seconds a started game waits for someone to
+// reconnect once every player has dropped.
+const AbandonTime = 30
+
+var ErrInvalidTransition = errors.New("invalid phase transition")
+
+var transitions = map[Phase][]Phase{
+	PhaseLobby:     {PhaseCountdown, PhaseRunning, PhaseClosed},
+	PhaseCountdown: {PhaseRunning, PhaseClosed},
+	PhaseRunning:   {PhasePaused, PhaseFinished, PhaseClosed},
+	PhasePaused:    {PhaseRunning, PhaseFinished, PhaseClosed},
+	PhaseFinished:  {PhaseClosed},
+}
+
+// CanTransition reports whether a game may move from p to next.
+func (p Phase) CanTransition(next Phase) bool {
+	for _, allowed := range transitions[p] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Started reports whether the game is past the lobby.
+func (p Phase) Started() bool {
+	return p != PhaseLobby && p != PhaseCountdown
+}
+
+/*
+Accepts reports whether players may send req during the phase: claims
+while the game is running, TEAM before it starts, and GAME_OVER once it has
+finished. Other than RESYNC, nothing is accepted in the lobby or while
+paused.
+*/
+func (p Phase) Accepts(req PlayerRequest) bool {
+	if req.Item == ResyncItem {
+		return true
+	}
+	if req.Item == TeamItem {
+		return !p.Started()
+	}
+	switch p {
+	case PhaseRunning:
+		return req.Item != GameOverItem
+	case PhaseFinished:
+		return req.Item == GameOverItem
+	}
+	return false
+}
+
+// setPhase moves the game to next and tells every player.
+func (m *Manager) setPhase(next Phase) error {
+	if !m.phase.CanTransition(next) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, m.phase, next)
+	}
+	m.phase = next
+	m.record(LogEntry{Type: LogPhase, Phase: next})
+	m.broadcast(GameEvent{Type: "Phase", Phase: next})
+	return nil
+}
@@ -1,16 +1,32 @@
 package game
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	protocol "server/protocol"
+
 	"github.com/gorilla/websocket"
 )
 
 type Player struct {
-	Username         string          `json:"username"` // identifies the player
-	Connection       *websocket.Conn `json:"-"`        // WebSocket connection to the server (e.g. *websocket.Conn)
-	Color            string          `json:"color"`    // hex color, unique within the game
-	Code             string          `json:"code"`     // game code this player belongs to
-	OutboundRequests chan GameEvent  `json:"-"`
-	connClosed       chan struct{}   // closes when Read() terminates, so Write() knows to terminate
+	Username     string               `json:"username"` // identifies the player
+	Connection   *websocket.Conn      `json:"-"`        // WebSocket connection to the server (e.g. *websocket.Conn)
+	Color        string               `json:"color"`    // hex color, unique within the game
+	Code         string               `json:"code"`     // game code this player belongs to
+	Token        string               `json:"-"`        // session token that lets this player reconnect
+	outbox       *outbox              // events waiting for Write
+	connClosed   chan struct{}        // closes when Read() terminates, so Write() knows to terminate
+	writerDone   chan struct{}        // closes when Write() terminates; nil if it isn't running
+	version      int                  // protocol version the connection speaks
+	connMu       sync.Mutex           // guards the connection fields, which change on reconnect
+	disconnected atomic.Bool          // set by the Manager while a started game waits for this player to reconnect
+	team         atomic.Pointer[Team] // set by the Manager in team games; fixed once the game starts
 }
 
 type PlayerMetaData struct {
@@ -20,53 +36,174 @@ type PlayerMetaData struct {
 
 func NewPlayer(username string, connection *websocket.Conn, color string, code string) *Player {
 	return &Player{
-		Username:         username,
-		Connection:       connection,
-		Color:            color,
-		Code:             code,
-		OutboundRequests: make(chan GameEvent, 64),
-		connClosed:       make(chan struct{}),
+		Username:   username,
+		Connection: connection,
+		Color:      color,
+		Code:       code,
+		Token:      newToken(),
+		outbox:     newOutbox(),
+		connClosed: make(chan struct{}),
 	}
 }
 
+// Connected reports whether the player is in the game right now, as opposed
+// to having dropped mid-game.
+func (p *Player) Connected() bool {
+	return !p.disconnected.Load()
+}
+
+// MarshalJSON adds whether the player is connected, and their team, to the player's fields.
+func (p *Player) MarshalJSON() ([]byte, error) {
+	var team string
+	if t := p.Team(); t != nil {
+		team = t.Name
+	}
+	return json.Marshal(struct {
+		Username  string `json:"username"`
+		Color     string `json:"color"`
+		Code      string `json:"code"`
+		Connected bool   `json:"connected"`
+		Team      string `json:"team,omitempty"`
+	}{p.Username, p.Color, p.Code, p.Connected(), team})
+}
+
+// newToken returns a random session token.
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// HasToken reports whether token is this player's session token.
+func (p *Player) HasToken(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(p.Token)) == 1
+}
+
+// connection returns the player's current connection and the channel that
+// closes when its reader stops.
+func (p *Player) connection() (*websocket.Conn, chan struct{}) {
+	p.connMu.Lock()
+	defer p.connMu.Unlock()
+	return p.Connection, p.connClosed
+}
+
+/*
+Attach gives the player a new connection speaking the given protocol
+version. Any old one is closed, and Attach waits for its Write routine to
+stop so that no queued event is written to a dead connection once the new
+one starts.
+*/
+func (p *Player) Attach(conn *websocket.Conn, version int) {
+	p.connMu.Lock()
+	old, oldWriter := p.Connection, p.writerDone
+	p.Connection = conn
+	p.version = version
+	p.connClosed = make(chan struct{})
+	p.writerDone = nil
+	p.connMu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	if oldWriter != nil {
+		<-oldWriter
+	}
+	if old != nil {
+		// whatever the old connection missed is stale; a reconnecting client gets a snapshot
+		p.outbox.take()
+	}
+}
+
+// send queues an event for the player. A player so far behind that their
+// queue overflows is disconnected.
+func (p *Player) send(event GameEvent) {
+	if p.outbox.push(event) {
+		return
+	}
+	slowDisconnects.Add(1)
+	if conn, _ := p.connection(); conn != nil {
+		conn.Close()
+	}
+}
+
+// Pending removes and returns the events queued for the player. Write does
+// this for connected players; it's for inspecting players who have none.
+func (p *Player) Pending() []GameEvent {
+	return p.outbox.take()
+}
+
+// Start runs the player's Read and Write routines on its current connection.
+func (p *Player) Start(m *Manager) {
+	p.connMu.Lock()
+	p.writerDone = make(chan struct{})
+	p.connMu.Unlock()
+	go p.Read(m)
+	go p.Write()
+}
+
 func (p *Player) Write() {
-	defer p.Connection.Close()
+	p.connMu.Lock()
+	conn, closed, done, version := p.Connection, p.connClosed, p.writerDone, p.version
+	p.connMu.Unlock()
+	if done != nil {
+		defer close(done)
+	}
+	defer conn.Close()
+	w := &eventWriter{conn: conn, version: version}
 	for {
 		select {
-		case event, ok := <-p.OutboundRequests:
-			if !ok {
-				return
+		case <-p.outbox.ready:
+			for _, event := range p.outbox.take() {
+				if err := w.write(event); err != nil {
+					return
+				}
+				eventsSent.Add(1)
 			}
-			if err := p.Connection.WriteJSON(event); err != nil {
-				return
-			}
-		case <-p.connClosed:
+		case <-closed:
 			return
 		}
 	}
 }
 
+/*
+Read forwards the player's claims to the game. Identity comes from the
+connection: username and code may be omitted, and a request naming another
+player or game is dropped with an Error frame instead of being credited.
+*/
 func (p *Player) Read(m *Manager) {
-	defer p.Connection.Close()
-	defer close(p.connClosed)
+	p.connMu.Lock()
+	conn, closed, version := p.Connection, p.connClosed, p.version
+	p.connMu.Unlock()
+	defer m.send(leaveMsg{player: p, conn: conn})
+	defer conn.Close()
+	defer close(closed)
 	for {
-		var req PlayerRequest
-		if err := p.Connection.ReadJSON(&req); err != nil {
+		req, err := readRequest(conn, version)
+		if errors.Is(err, errBadMessage) {
+			p.SendError(protocol.ErrBadMessage, err.Error())
+			continue
+		}
+		if err != nil {
 			return
 		}
 
-		if req.Username == "" || req.Code == "" || req.Item == "" {
+		if req.Item == "" {
 			continue
 		}
 
-		_, playerExists := m.Players[req.Username]
-		if (req.Code != m.Code) || !playerExists {
+		if (req.Username != "" && req.Username != p.Username) || (req.Code != "" && req.Code != p.Code) {
+			p.SendError(protocol.ErrWrongPlayer, "request does not match this connection's player")
 			continue
 		}
+		req.Username = p.Username
+		req.Code = p.Code
 
-		select {
-		case m.InboundRequests <- req:
-		default: // don't block the channel
+		if !m.Claim(req) && req.isGuess() {
+			p.sendGuess(req, protocol.GuessRateLimited, "", nil)
 		}
 	}
 }
+
+// SendError tells the player one of their requests was rejected.
+func (p *Player) SendError(code protocol.ErrorCode, msg string) {
+	p.send(GameEvent{Type: "Error", Error: msg, Code: code})
+}
@@ -0,0 +1,170 @@
+package game
+
+import (
+	"maps"
+	"time"
+
+	clock "server/clock"
+
+	"github.com/gorilla/websocket"
+)
+
+// MaxReplaySpeed is the most a replay can be sped up.
+const MaxReplaySpeed = 32
+
+// ReplayTick is how often a replay checks for events that have come due.
+const ReplayTick = 50 * time.Millisecond
+
+// replay rebuilds a finished game from its log, one entry at a time.
+type replay struct {
+	results Results
+	board   map[string]*Player
+	players map[string]*Player
+	teams   []*Team
+	seq     int
+	started bool
+}
+
+func newReplay(r Results) *replay {
+	board := make(map[string]*Player)
+	for _, e := range r.Standings {
+		for _, item := range e.Items {
+			board[item] = nil
+		}
+	}
+	for _, item := range r.Unfound {
+		board[item] = nil
+	}
+	rp := &replay{results: r, board: board, players: make(map[string]*Player)}
+	for _, s := range r.Teams {
+		rp.teams = append(rp.teams, &Team{Name: s.Name, Color: s.Color})
+	}
+	return rp
+}
+
+// team returns the replayed game's team called name, or nil.
+func (r *replay) team(name string) *Team {
+	for _, t := range r.teams {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// snapshot is what a player joining the game before anyone else would have got.
+func (r *replay) snapshot() GameEvent {
+	var teams []Team
+	for _, t := range r.teams {
+		teams = append(teams, *t)
+	}
+	return GameEvent{Type: "Snapshot", State: maps.Clone(r.board), Phase: PhaseLobby, Players: maps.Clone(r.players), Teams: teams}
+}
+
+// apply plays e and returns the events the live game broadcast for it.
+func (r *replay) apply(e LogEntry) []GameEvent {
+	switch e.Type {
+	case LogJoin:
+		p := r.players[e.Player]
+		if p != nil {
+			p.disconnected.Store(false)
+		} else {
+			p = NewPlayer(e.Player, nil, e.Color, r.results.Code)
+			r.players[e.Player] = p
+		}
+		if t := r.team(e.Team); t != nil {
+			p.team.Store(t)
+		}
+		return []GameEvent{{Type: "Players", Players: maps.Clone(r.players)}}
+	case LogTeam:
+		p, t := r.players[e.Player], r.team(e.Team)
+		if p == nil || t == nil {
+			return nil
+		}
+		p.team.Store(t)
+		return []GameEvent{{Type: "Players", Players: maps.Clone(r.players)}}
+	case LogLeave:
+		if r.started {
+			if p := r.players[e.Player]; p != nil {
+				p.disconnected.Store(true)
+			}
+		} else {
+			delete(r.players, e.Player)
+		}
+		return []GameEvent{{Type: "Players", Players: maps.Clone(r.players)}}
+	case LogPhase:
+		events := []GameEvent{{Type: "Phase", Phase: e.Phase}}
+		switch {
+		case e.Phase == PhaseRunning && !r.started:
+			r.started = true
+			events = append(events, GameEvent{Type: "Start"})
+		case e.Phase == PhaseFinished:
+			events = append(events, GameEvent{Type: "Leaderboard", Leaderboard: r.results.Standings, Unfound: r.results.Unfound, TeamBoard: r.results.Teams, Coop: r.results.Coop})
+		}
+		return events
+	case LogClaim:
+		p := r.players[e.Player]
+		if p == nil {
+			return nil
+		}
+		r.board[e.Item] = p
+		r.seq++
+		return []GameEvent{{Type: "Claimed", Seq: r.seq, Item: e.Item, Player: p}}
+	case LogSteal:
+		p, from := r.players[e.Player], r.players[e.From]
+		if p == nil || from == nil {
+			return nil
+		}
+		r.board[e.Item] = p
+		r.seq++
+		return []GameEvent{{Type: "Stolen", Seq: r.seq, Item: e.Item, Player: p, From: from}}
+	}
+	return nil
+}
+
+/*
+StreamReplay plays a finished game back over conn, in the events a player
+of the live game got, speed times as fast as it happened: a Snapshot of the
+empty board, then Players, Phase, Start, Claimed, Stolen and finally Leaderboard,
+each when its log entry comes due. Time events are not replayed. It returns
+once the Leaderboard is written or the client goes away, and doesn't close
+conn.
+*/
+func StreamReplay(conn *websocket.Conn, version int, r Results, speed float64, clk clock.Clock) error {
+	gone := make(chan struct{})
+	go func() {
+		// reads are only for noticing the client leave
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	write := (&eventWriter{conn: conn, version: version}).write
+
+	rp := newReplay(r)
+	if err := write(rp.snapshot()); err != nil {
+		return err
+	}
+	ticker := clk.NewTicker(ReplayTick)
+	defer ticker.Stop()
+	start := clk.Now()
+	for i := 0; i < len(r.Events); {
+		var now time.Time
+		select {
+		case now = <-ticker.C():
+		case <-gone:
+			return nil
+		}
+		elapsed := now.Sub(start).Seconds() * speed
+		for ; i < len(r.Events) && r.Events[i].Elapsed-r.Events[0].Elapsed <= elapsed; i++ {
+			for _, e := range rp.apply(r.Events[i]) {
+				if err := write(e); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
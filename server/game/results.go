@@ -0,0 +1,153 @@
+package game
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A TieBreaker decides the order of players who claimed the same number of squares.
+type TieBreaker string
+
+const (
+	TieBreakShared    TieBreaker = "shared"    // tied players share a rank
+	TieBreakLastClaim TieBreaker = "lastClaim" // whoever made their last claim first ranks higher
+)
+
+// ParseTieBreaker validates s, defaulting to TieBreakShared when empty.
+func ParseTieBreaker(s string) (TieBreaker, error) {
+	switch TieBreaker(s) {
+	case "":
+		return TieBreakShared, nil
+	case TieBreakShared, TieBreakLastClaim:
+		return TieBreaker(s), nil
+	}
+	return "", errors.New("tieBreaker must be one of shared, lastClaim")
+}
+
+// Results are a finished game's final standings, with what it took to get
+// there. They outlive the game: see Manager.Results and Options.OnFinish.
+type Results struct {
+	Title      string             `json:"title"`
+	Code       string             `json:"code"`
+	Settings   Settings           `json:"settings"`
+	StartedAt  time.Time          `json:"startedAt"`
+	FinishedAt time.Time          `json:"finishedAt"`
+	Standings  []LeaderboardEntry `json:"standings"`       // every player who played, best first
+	Teams      []TeamStanding     `json:"teams,omitempty"` // in team games: every team, best first
+	Coop       *CoopResult        `json:"coop,omitempty"`  // in cooperative games
+	Unfound    []string           `json:"unfound"`         // squares nobody claimed
+	Events     []LogEntry         `json:"events"`          // every join, leave, phase change and claim, in order
+}
+
+// Settings are the options a game was played with.
+type Settings struct {
+	LobbyTime  int        `json:"lobbyTime"`
+	GameTime   int        `json:"gameTime"`
+	Strictness Strictness `json:"strictness"`
+	TieBreaker TieBreaker `json:"tieBreaker"`
+	Mode       Mode       `json:"mode"`
+	// Steal mode's rules, when it is on.
+	Steal         bool `json:"steal,omitempty"`
+	StealCooldown int  `json:"stealCooldown,omitempty"`
+	MaxSteals     int  `json:"maxSteals,omitempty"`
+}
+
+// LeaderboardEntry is one player's final result.
+type LeaderboardEntry struct {
+	Rank     int      `json:"rank"` // from 1; tied players share a rank and the next rank is skipped
+	Username string   `json:"username"`
+	Color    string   `json:"color"`
+	Team     string   `json:"team,omitempty"` // in team games
+	Count    int      `json:"correct"`
+	Items    []string `json:"items"` // the squares they hold, in the order they were claimed
+
+	lastClaim time.Time
+}
+
+/*
+leaderboard ranks every player who played, most squares first. Players
+with the same score are ordered by the TieBreaker and share a rank if it
+can't separate them; within a rank they are listed by username.
+*/
+func (m *Manager) leaderboard() []LeaderboardEntry {
+	byPlayer := make(map[*Player]*LeaderboardEntry, len(m.correct))
+	for p, n := range m.correct {
+		byPlayer[p] = &LeaderboardEntry{Username: p.Username, Color: p.Color, Count: n, Items: []string{}}
+		if t := p.Team(); t != nil {
+			byPlayer[p].Team = t.Name
+		}
+	}
+	for _, item := range m.claimOrder() {
+		e := byPlayer[m.board[item]]
+		if e == nil {
+			continue // claimed by a player who has since left
+		}
+		e.Items = append(e.Items, item)
+		e.lastClaim = m.claimedAt[item]
+	}
+
+	lst := make([]LeaderboardEntry, 0, len(byPlayer))
+	for _, e := range byPlayer {
+		lst = append(lst, *e)
+	}
+	rank(lst, m.opts.TieBreaker,
+		func(e LeaderboardEntry) (int, time.Time) { return e.Count, e.lastClaim },
+		func(e LeaderboardEntry) string { return e.Username },
+		func(e *LeaderboardEntry, r int) { e.Rank = r })
+	return lst
+}
+
+/*
+rank sorts lst best first, by the number of squares in score, and numbers it
+from 1. Entries with the same score are ordered by tb and share a rank if it
+can't separate them; within a rank they are ordered by name.
+*/
+func rank[T any](lst []T, tb TieBreaker, score func(T) (int, time.Time), name func(T) string, setRank func(*T, int)) {
+	tied := func(a, b T) int {
+		countA, lastA := score(a)
+		countB, lastB := score(b)
+		if c := cmp.Compare(countB, countA); c != 0 || tb != TieBreakLastClaim {
+			return c
+		}
+		return lastA.Compare(lastB)
+	}
+	slices.SortFunc(lst, func(a, b T) int {
+		return cmp.Or(tied(a, b), strings.Compare(name(a), name(b)))
+	})
+	r := 0
+	for i := range lst {
+		if i == 0 || tied(lst[i-1], lst[i]) != 0 {
+			r = i + 1
+		}
+		setRank(&lst[i], r)
+	}
+}
+
+// claimOrder returns the claimed squares, earliest claim first.
+func (m *Manager) claimOrder() []string {
+	items := make([]string, 0, m.squaresTaken)
+	for item, p := range m.board {
+		if p != nil {
+			items = append(items, item)
+		}
+	}
+	slices.SortFunc(items, func(a, b string) int {
+		return cmp.Or(m.claimedAt[a].Compare(m.claimedAt[b]), strings.Compare(a, b))
+	})
+	return items
+}
+
+// unfound returns the squares nobody claimed, sorted.
+func (m *Manager) unfound() []string {
+	items := []string{}
+	for item, p := range m.board {
+		if p == nil {
+			items = append(items, item)
+		}
+	}
+	slices.Sort(items)
+	return items
+}
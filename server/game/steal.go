@@ -0,0 +1,59 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	protocol "server/protocol"
+)
+
+// Defaults for steal mode, used when the host doesn't pick.
+const (
+	DefaultStealCooldown = 10 // seconds
+	DefaultMaxSteals     = 2
+)
+
+// Limits on what a host can pick for steal mode.
+const (
+	MaxStealCooldown = 300
+	MaxStealLimit    = 20
+)
+
+// ValidateSteal checks a host's steal mode settings.
+func ValidateSteal(cooldown, maxSteals int) error {
+	if cooldown < 0 || cooldown > MaxStealCooldown {
+		return fmt.Errorf("steal cooldown must be between 0 and %ds", MaxStealCooldown)
+	}
+	if maxSteals < 1 || maxSteals > MaxStealLimit {
+		return fmt.Errorf("a square can be stolen between 1 and %d times", MaxStealLimit)
+	}
+	return nil
+}
+
+// stealOutcome says whether player may take item from owner now:
+// GuessStolen if so, otherwise the outcome to answer their guess with.
+func (m *Manager) stealOutcome(player, owner *Player, item string) protocol.GuessOutcome {
+	switch {
+	case !m.opts.Steal || owner == player:
+		return protocol.GuessAlreadyClaimed
+	case owner.Team() != nil && owner.Team() == player.Team():
+		return protocol.GuessAlreadyClaimed // teammates' squares already count for the team
+	case m.steals[item] >= m.opts.MaxSteals:
+		return protocol.GuessLocked
+	case m.clock.Now().Sub(m.claimedAt[item]) < time.Duration(m.opts.StealCooldown)*time.Second:
+		return protocol.GuessCooldown
+	}
+	return protocol.GuessStolen
+}
+
+// steal takes item from owner and gives it to player.
+func (m *Manager) steal(player, owner *Player, item string) {
+	m.board[item] = player
+	m.claimedAt[item] = m.clock.Now()
+	m.steals[item]++
+	m.correct[owner] -= 1
+	m.correct[player] += 1
+	m.seq += 1
+	m.record(LogEntry{Type: LogSteal, Player: player.Username, Item: item, From: owner.Username})
+	m.broadcast(GameEvent{Type: "Stolen", Seq: m.seq, Item: item, Player: player, From: owner})
+}
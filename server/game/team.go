@@ -0,0 +1,164 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	protocol "server/protocol"
+)
+
+// MaxTeams is the most teams a game can have; each gets one of PlayerColors.
+var MaxTeams = len(PlayerColors)
+
+// TeamItem is the request a player sends in the lobby to move to the team
+// named in PlayerRequest.Team.
+const TeamItem = "TEAM"
+
+// A Team is a side in a team game. Squares its members claim count for it.
+type Team struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// TeamStanding is one team's final result. Members are ranked among
+// themselves by what they contributed.
+type TeamStanding struct {
+	Rank    int                `json:"rank"` // from 1; tied teams share a rank
+	Name    string             `json:"name"`
+	Color   string             `json:"color"`
+	Count   int                `json:"correct"`
+	Members []LeaderboardEntry `json:"members"`
+
+	lastClaim time.Time
+}
+
+// ValidateTeams checks the team names a host asked for: none, or between 2
+// and MaxTeams distinct, non-blank names.
+func ValidateTeams(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	if len(names) < 2 || len(names) > MaxTeams {
+		return fmt.Errorf("a team game needs between 2 and %d teams", MaxTeams)
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("team names can't be blank")
+		}
+		if seen[name] {
+			return fmt.Errorf("team %q is listed twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+type teamsMsg struct {
+	names []string
+	reply chan struct{}
+}
+
+func (msg teamsMsg) handle(m *Manager) {
+	m.teams = nil
+	for i, name := range msg.names {
+		m.teams = append(m.teams, &Team{Name: name, Color: PlayerColors[i%len(PlayerColors)]})
+	}
+	close(msg.reply)
+}
+
+/*
+SetTeams makes the game a team game with teams of the given names, which
+should have passed ValidateTeams. Call it before anyone joins: players are
+put in the smallest team as they join, and may switch teams in the lobby
+with TeamItem.
+*/
+func (m *Manager) SetTeams(names []string) {
+	reply := make(chan struct{})
+	ask(m, teamsMsg{names: names, reply: reply}, reply)
+}
+
+// Team returns the player's team, or nil outside team games.
+func (p *Player) Team() *Team {
+	return p.team.Load()
+}
+
+// team returns the game's team called name, or nil.
+func (m *Manager) team(name string) *Team {
+	for _, t := range m.teams {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// smallestTeam returns the team with the fewest players, the first listed
+// if several are equal, so joining players balance the teams.
+func (m *Manager) smallestTeam() *Team {
+	size := make(map[*Team]int)
+	for _, p := range m.players {
+		size[p.Team()]++
+	}
+	var smallest *Team
+	for _, t := range m.teams {
+		if smallest == nil || size[t] < size[smallest] {
+			smallest = t
+		}
+	}
+	return smallest
+}
+
+// pickTeam moves p to the team they asked for with TeamItem.
+func (m *Manager) pickTeam(p *Player, req PlayerRequest) {
+	t := m.team(req.Team)
+	if t == nil {
+		p.SendError(protocol.ErrUnknownTeam, fmt.Sprintf("this game has no team %q", req.Team))
+		return
+	}
+	p.team.Store(t)
+	m.record(LogEntry{Type: LogTeam, Player: p.Username, Team: t.Name})
+	m.broadcastPlayers()
+}
+
+// teamsCopy returns the game's teams for an event, or nil outside team games.
+func (m *Manager) teamsCopy() []Team {
+	if len(m.teams) == 0 {
+		return nil
+	}
+	teams := make([]Team, 0, len(m.teams))
+	for _, t := range m.teams {
+		teams = append(teams, *t)
+	}
+	return teams
+}
+
+// teamLeaderboard ranks the teams by the squares their members hold, given
+// the players' standings. It is nil outside team games.
+func (m *Manager) teamLeaderboard(standings []LeaderboardEntry) []TeamStanding {
+	if len(m.teams) == 0 {
+		return nil
+	}
+	lst := make([]TeamStanding, 0, len(m.teams))
+	for _, t := range m.teams {
+		s := TeamStanding{Name: t.Name, Color: t.Color, Members: []LeaderboardEntry{}}
+		for _, e := range standings {
+			if e.Team != t.Name {
+				continue
+			}
+			s.Count += e.Count
+			s.Members = append(s.Members, e)
+			if e.lastClaim.After(s.lastClaim) {
+				s.lastClaim = e.lastClaim
+			}
+		}
+		lst = append(lst, s)
+	}
+	rank(lst, m.opts.TieBreaker,
+		func(s TeamStanding) (int, time.Time) { return s.Count, s.lastClaim },
+		func(s TeamStanding) string { return s.Name },
+		func(s *TeamStanding, r int) { s.Rank = r })
+	return lst
+}
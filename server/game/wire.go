@@ -0,0 +1,203 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	protocol "server/protocol"
+
+	"github.com/gorilla/websocket"
+)
+
+var errBadMessage = errors.New("unknown message type")
+
+// wire returns the event as it is written to a client speaking version.
+func (e GameEvent) wire(version int) any {
+	if version < protocol.Version {
+		return e
+	}
+	switch e.Type {
+	case "Time":
+		return protocol.Time{Type: protocol.TypeTime, TimeLeft: e.TimeLeft}
+	case "Players":
+		return protocol.Players{Type: protocol.TypePlayers, Players: wirePlayers(e.Players)}
+	case "Start":
+		return protocol.Start{Type: protocol.TypeStart}
+	case "Phase":
+		return protocol.Phase{Type: protocol.TypePhase, Phase: string(e.Phase)}
+	case "Claimed":
+		return protocol.Claimed{Type: protocol.TypeClaimed, Seq: e.Seq, Item: e.Item, Player: e.Player.Username, Team: teamName(e.Player)}
+	case "Stolen":
+		return protocol.Stolen{Type: protocol.TypeStolen, Seq: e.Seq, Item: e.Item, Player: e.Player.Username, From: e.From.Username, Team: teamName(e.Player)}
+	case "Snapshot":
+		board := make(map[string]string, len(e.State))
+		for item, p := range e.State {
+			board[item] = ""
+			if p != nil {
+				board[item] = p.Username
+			}
+		}
+		return protocol.Snapshot{
+			Type:        protocol.TypeSnapshot,
+			Seq:         e.Seq,
+			Phase:       string(e.Phase),
+			Started:     e.Started,
+			TimeLeft:    e.TimeLeft,
+			Board:       board,
+			Players:     wirePlayers(e.Players),
+			Teams:       wireTeams(e.Teams),
+			Leaderboard: wireLeaderboard(e.Leaderboard),
+			Unfound:     e.Unfound,
+			TeamBoard:   wireTeamBoard(e.TeamBoard),
+			Coop:        wireCoop(e.Coop),
+		}
+	case "Leaderboard":
+		return protocol.Leaderboard{Type: protocol.TypeLeaderboard, Entries: wireLeaderboard(e.Leaderboard), Unfound: e.Unfound, Teams: wireTeamBoard(e.TeamBoard), Coop: wireCoop(e.Coop)}
+	case "Error":
+		return protocol.NewError(e.Code, e.Error)
+	case "Guess":
+		guess := protocol.Guess{Type: protocol.TypeGuess, ID: e.RequestID, Outcome: e.Outcome, Item: e.Item}
+		if e.Player != nil && e.Outcome != protocol.GuessClaimed {
+			guess.ClaimedBy = e.Player.Username
+		}
+		return guess
+	}
+	return e // an event type with no message yet goes out in the legacy format
+}
+
+/*
+An eventWriter writes one connection's events in the protocol version it
+speaks. The original client draws its board only from Board events carrying
+the whole board, so on the legacy protocol each Snapshot, Claimed and Stolen
+event is followed by one, built up in board.
+*/
+type eventWriter struct {
+	conn    *websocket.Conn
+	version int
+	board   map[string]*Player // legacy only: the board as the client last got it
+}
+
+func (w *eventWriter) write(e GameEvent) error {
+	w.conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	if err := w.conn.WriteJSON(e.wire(w.version)); err != nil {
+		return err
+	}
+	if w.version != protocol.LegacyVersion || !w.update(e) {
+		return nil
+	}
+	w.conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	return w.conn.WriteJSON(GameEvent{Type: "Board", State: w.board})
+}
+
+// update applies e to the legacy board and reports whether it changed.
+func (w *eventWriter) update(e GameEvent) bool {
+	switch e.Type {
+	case "Snapshot":
+		w.board = maps.Clone(e.State)
+		return true
+	case "Claimed", "Stolen":
+		if w.board == nil {
+			return false // the Snapshot on its way has it
+		}
+		w.board[e.Item] = e.Player
+		return true
+	}
+	return false
+}
+
+func wirePlayers(players map[string]*Player) []protocol.Player {
+	out := make([]protocol.Player, 0, len(players))
+	for _, p := range players {
+		out = append(out, protocol.Player{Username: p.Username, Color: p.Color, Connected: p.Connected(), Team: teamName(p)})
+	}
+	slices.SortFunc(out, func(a, b protocol.Player) int { return strings.Compare(a.Username, b.Username) })
+	return out
+}
+
+func wireLeaderboard(entries []LeaderboardEntry) []protocol.LeaderboardEntry {
+	if entries == nil {
+		return nil
+	}
+	out := make([]protocol.LeaderboardEntry, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, protocol.LeaderboardEntry{Rank: e.Rank, Username: e.Username, Color: e.Color, Team: e.Team, Correct: e.Count, Items: e.Items})
+	}
+	return out
+}
+
+func wireTeams(teams []Team) []protocol.Team {
+	if teams == nil {
+		return nil
+	}
+	out := make([]protocol.Team, 0, len(teams))
+	for _, t := range teams {
+		out = append(out, protocol.Team{Name: t.Name, Color: t.Color})
+	}
+	return out
+}
+
+func wireTeamBoard(standings []TeamStanding) []protocol.TeamStanding {
+	if standings == nil {
+		return nil
+	}
+	out := make([]protocol.TeamStanding, 0, len(standings))
+	for _, s := range standings {
+		out = append(out, protocol.TeamStanding{Rank: s.Rank, Name: s.Name, Color: s.Color, Correct: s.Count, Members: wireLeaderboard(s.Members)})
+	}
+	return out
+}
+
+func wireCoop(r *CoopResult) *protocol.CoopResult {
+	if r == nil {
+		return nil
+	}
+	return &protocol.CoopResult{Success: r.Success, Found: r.Found, Total: r.Total, Completion: r.Completion, Missed: r.Missed}
+}
+
+// teamName returns p's team's name, or "" outside team games.
+func teamName(p *Player) string {
+	if t := p.Team(); t != nil {
+		return t.Name
+	}
+	return ""
+}
+
+/*
+readRequest reads the next request from a client speaking version. A
+message of a type the protocol doesn't define gives an error wrapping
+errBadMessage, after which the connection can still be read.
+*/
+func readRequest(conn *websocket.Conn, version int) (PlayerRequest, error) {
+	var req PlayerRequest
+	if version < protocol.Version {
+		err := conn.ReadJSON(&req)
+		return req, err
+	}
+	var msg struct {
+		Type protocol.MessageType `json:"type"`
+		ID   string               `json:"id"`
+		Item string               `json:"item"`
+		Team string               `json:"team"`
+	}
+	if err := conn.ReadJSON(&msg); err != nil {
+		return req, err
+	}
+	req.ID = msg.ID
+	switch msg.Type {
+	case protocol.TypeClaim:
+		req.Item = msg.Item
+	case protocol.TypeResync:
+		req.Item = ResyncItem
+	case protocol.TypeGameOver:
+		req.Item = GameOverItem
+	case protocol.TypePickTeam:
+		req.Item, req.Team = TeamItem, msg.Team
+	default:
+		return req, fmt.Errorf("%w: %q", errBadMessage, msg.Type)
+	}
+	return req, nil
+}
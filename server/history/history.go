@@ -0,0 +1,39 @@
+/*
+Package history keeps completed matches, so they can be looked back on
+after the game that played them has closed.
+*/
+package history
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	game "server/game"
+)
+
+// ErrNotFound is returned by a Store when no match has the requested ID.
+var ErrNotFound = errors.New("match not found")
+
+// DefaultPath is where the file store keeps matches, next to the trivia directory.
+var DefaultPath = "../history"
+
+// Match is a completed game: its quiz, settings, players, claims and final standings.
+type Match struct {
+	ID string `json:"id"`
+	game.Results
+}
+
+// NewMatch returns the match for a game's results. Game codes are reused,
+// so the ID also has the time the game finished.
+func NewMatch(r game.Results) *Match {
+	id := strings.ToLower(r.Code) + "-" + strconv.FormatInt(r.FinishedAt.UnixMilli(), 10)
+	return &Match{ID: id, Results: r}
+}
+
+// Store persists completed matches. Implementations must be safe for concurrent use.
+type Store interface {
+	List() ([]*Match, error) // oldest first
+	Get(id string) (*Match, error)
+	Put(m *Match) error // creates or replaces the match with m.ID
+}
@@ -0,0 +1,41 @@
+package history
+
+import (
+	"regexp"
+	"slices"
+
+	jsonstore "server/jsonstore"
+)
+
+var validID = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// FileStore keeps each match as <id>.json in a directory.
+type FileStore struct {
+	files *jsonstore.Dir[Match]
+}
+
+// NewFileStore returns a store in dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	files, err := jsonstore.New[Match](dir, validID, ErrNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{files: files}, nil
+}
+
+func (s *FileStore) List() ([]*Match, error) {
+	matches, err := s.files.List()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(matches, func(a, b *Match) int { return a.FinishedAt.Compare(b.FinishedAt) })
+	return matches, nil
+}
+
+func (s *FileStore) Get(id string) (*Match, error) {
+	return s.files.Get(id)
+}
+
+func (s *FileStore) Put(m *Match) error {
+	return s.files.Put(m.ID, m)
+}
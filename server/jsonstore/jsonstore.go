@@ -0,0 +1,130 @@
+/*
+Package jsonstore keeps values as JSON files in a directory, one file per
+ID. The library and history stores are built on it.
+*/
+package jsonstore
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Dir keeps each value as <id>.json in a directory. It is safe for concurrent use.
+type Dir[T any] struct {
+	dir      string
+	validID  *regexp.Regexp
+	notFound error
+	mu       sync.RWMutex
+}
+
+// New returns a Dir in dir, creating it if needed. An ID that doesn't match
+// validID, like one with a path separator, is never read or written: Get,
+// Put and Delete report notFound for it, as they do for a missing file.
+func New[T any](dir string, validID *regexp.Regexp, notFound error) (*Dir[T], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Dir[T]{dir: dir, validID: validID, notFound: notFound}, nil
+}
+
+func (d *Dir[T]) path(id string) (string, error) {
+	if !d.validID.MatchString(id) {
+		return "", d.notFound
+	}
+	return filepath.Join(d.dir, id+".json"), nil
+}
+
+// List returns every value in the directory, in no particular order. Files
+// that can't be read are skipped.
+func (d *Dir[T]) List() ([]*T, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	values := []*T{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		v, err := d.read(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (d *Dir[T]) Get(id string) (*T, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.read(id)
+}
+
+func (d *Dir[T]) read(id string) (*T, error) {
+	path, err := d.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, d.notFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Put writes v as id to a temporary file and renames it, so a crash never
+// leaves a half-written file behind.
+func (d *Dir[T]) Put(id string, v *T) error {
+	path, err := d.path(id)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	tmp, err := os.CreateTemp(d.dir, id+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d *Dir[T]) Delete(id string) error {
+	path, err := d.path(id)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d.notFound
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package library
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	trivia "server/trivia"
+)
+
+// ListHandler handles GET /library/quizzes: every published quiz, plus the
+// caller's own drafts.
+func ListHandler(lib *Library, w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r)
+	quizzes, err := lib.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "could not read the library")
+		return
+	}
+	infos := []QuizInfo{}
+	for _, q := range quizzes {
+		if q.Published || q.OwnedBy(token) {
+			infos = append(infos, info(q, token))
+		}
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// CreateHandler handles POST /library/quizzes. The caller may present a
+// bearer token to own the quiz with; otherwise one is generated and returned.
+func CreateHandler(lib *Library, w http.ResponseWriter, r *http.Request) {
+	var req QuizRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	q, token, err := lib.Create(bearerToken(r), req.Quiz, req.Published)
+	if err != nil {
+		writeLibraryError(w, err)
+		return
+	}
+	resp := QuizResponse{QuizInfo: info(q, token), Quiz: q.Quiz, OwnerToken: token}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// GetHandler handles GET /library/quizzes/{id}. Only the owner sees a
+// draft or the answers.
+func GetHandler(lib *Library, w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r)
+	q, err := lib.Get(r.PathValue("id"))
+	if err != nil {
+		writeLibraryError(w, err)
+		return
+	}
+	switch {
+	case q.OwnedBy(token):
+		writeJSON(w, http.StatusOK, QuizResponse{QuizInfo: info(q, token), Quiz: q.Quiz})
+	case q.Published:
+		writeJSON(w, http.StatusOK, info(q, token))
+	default:
+		writeLibraryError(w, ErrNotFound)
+	}
+}
+
+// UpdateHandler handles PUT /library/quizzes/{id}, replacing the whole quiz.
+func UpdateHandler(lib *Library, w http.ResponseWriter, r *http.Request) {
+	var req QuizRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	token := bearerToken(r)
+	q, err := lib.Update(r.PathValue("id"), token, req.Quiz, req.Published)
+	if err != nil {
+		writeLibraryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, QuizResponse{QuizInfo: info(q, token), Quiz: q.Quiz})
+}
+
+// DeleteHandler handles DELETE /library/quizzes/{id}.
+func DeleteHandler(lib *Library, w http.ResponseWriter, r *http.Request) {
+	if err := lib.Delete(r.PathValue("id"), bearerToken(r)); err != nil {
+		writeLibraryError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func info(q *Quiz, token string) QuizInfo {
+	entry := trivia.Entry{Quiz: q.Quiz, File: SourceName}
+	return QuizInfo{
+		QuizSummary: entry.Summary(),
+		ID:          q.ID,
+		Published:   q.Published,
+		Owned:       q.OwnedBy(token),
+		CreatedAt:   q.CreatedAt,
+		UpdatedAt:   q.UpdatedAt,
+	}
+}
+
+// bearerToken returns the token from an "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func writeLibraryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrForbidden):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrTitleTaken):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvalid):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "could not save the library")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, ErrorResponse{Error: msg})
+}
@@ -0,0 +1,24 @@
+package library
+
+import (
+	"net/http"
+)
+
+// RegisterRoutes registers the /library/quizzes CRUD routes on mux.
+func RegisterRoutes(mux *http.ServeMux, lib *Library) {
+	mux.HandleFunc("GET /library/quizzes", func(w http.ResponseWriter, r *http.Request) {
+		ListHandler(lib, w, r)
+	})
+	mux.HandleFunc("POST /library/quizzes", func(w http.ResponseWriter, r *http.Request) {
+		CreateHandler(lib, w, r)
+	})
+	mux.HandleFunc("GET /library/quizzes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		GetHandler(lib, w, r)
+	})
+	mux.HandleFunc("PUT /library/quizzes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		UpdateHandler(lib, w, r)
+	})
+	mux.HandleFunc("DELETE /library/quizzes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		DeleteHandler(lib, w, r)
+	})
+}
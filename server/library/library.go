@@ -0,0 +1,213 @@
+package library
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	trivia "server/trivia"
+)
+
+// SourceName is the trivia category published library quizzes appear under,
+// e.g. in /trivia/files and /trivia/keys?file=library.
+const SourceName = "library"
+
+// DefaultPath is where the file store keeps quizzes, next to the trivia directory.
+var DefaultPath = "../library"
+
+var (
+	ErrForbidden  = errors.New("only the quiz's creator can change it")
+	ErrTitleTaken = errors.New("a quiz with this title already exists")
+	ErrInvalid    = errors.New("invalid quiz")
+)
+
+/*
+A Library manages user-authored quizzes. Drafts are only visible to
+their creator; published quizzes are added to the trivia catalog under
+SourceName so they can be played exactly like the bundled ones.
+*/
+type Library struct {
+	store     Store
+	catalog   *trivia.LiveCatalog
+	mu        sync.Mutex                    // serializes writes so title checks see a consistent store
+	published atomic.Pointer[[]trivia.Quiz] // what the catalog source returns
+}
+
+// New returns a library backed by store and publishes its quizzes into catalog.
+func New(store Store, catalog *trivia.LiveCatalog) (*Library, error) {
+	l := &Library{store: store, catalog: catalog}
+	if err := l.refreshPublished(); err != nil {
+		return nil, err
+	}
+	changes, err := catalog.SetSource(SourceName, l.publishedQuizzes)
+	if err != nil {
+		return nil, err
+	}
+	for _, title := range changes.Added {
+		log.Printf("library: published %q", title)
+	}
+	return l, nil
+}
+
+func (l *Library) publishedQuizzes() []trivia.Quiz {
+	return *l.published.Load()
+}
+
+// refreshPublished rereads the store's published quizzes for the catalog.
+func (l *Library) refreshPublished() error {
+	all, err := l.store.List()
+	if err != nil {
+		return err
+	}
+	published := []trivia.Quiz{}
+	for _, q := range all {
+		if q.Published {
+			published = append(published, q.Quiz)
+		}
+	}
+	l.published.Store(&published)
+	return nil
+}
+
+/*
+sync pushes the store's published quizzes into the catalog after a write.
+The write has already succeeded by then, so a failure is only logged: the
+catalog catches up on the next successful sync.
+*/
+func (l *Library) sync() {
+	if err := l.refreshPublished(); err != nil {
+		log.Printf("library: publishing quizzes: %v", err)
+		return
+	}
+	l.catalog.ReloadSources()
+}
+
+// List returns every quiz in the library, drafts included.
+func (l *Library) List() ([]*Quiz, error) {
+	return l.store.List()
+}
+
+// Get returns the quiz with id.
+func (l *Library) Get(id string) (*Quiz, error) {
+	return l.store.Get(id)
+}
+
+// Create stores a new quiz owned by token. If token is empty a new one is
+// generated; either way it is returned and must be presented to edit the quiz.
+func (l *Library) Create(token string, quiz trivia.Quiz, published bool) (*Quiz, string, error) {
+	if err := trivia.ValidateUserQuiz(&quiz); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if token == "" {
+		token = randomHex(16)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.checkTitle(quiz.Title, ""); err != nil {
+		return nil, "", err
+	}
+	now := time.Now().UTC()
+	q := &Quiz{
+		ID:        randomHex(6),
+		OwnerHash: hashToken(token),
+		Published: published,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Quiz:      quiz,
+	}
+	if err := l.store.Put(q); err != nil {
+		return nil, "", err
+	}
+	l.sync()
+	return q, token, nil
+}
+
+// Update replaces the quiz with id, if token owns it.
+func (l *Library) Update(id, token string, quiz trivia.Quiz, published bool) (*Quiz, error) {
+	if err := trivia.ValidateUserQuiz(&quiz); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	q, err := l.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !q.OwnedBy(token) {
+		return nil, ErrForbidden
+	}
+	if err := l.checkTitle(quiz.Title, id); err != nil {
+		return nil, err
+	}
+	q.Quiz = quiz
+	q.Published = published
+	q.UpdatedAt = time.Now().UTC()
+	if err := l.store.Put(q); err != nil {
+		return nil, err
+	}
+	l.sync()
+	return q, nil
+}
+
+// Delete removes the quiz with id, if token owns it. Games already created
+// from it keep running.
+func (l *Library) Delete(id, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	q, err := l.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if !q.OwnedBy(token) {
+		return ErrForbidden
+	}
+	if err := l.store.Delete(id); err != nil {
+		return err
+	}
+	l.sync()
+	return nil
+}
+
+// checkTitle rejects a title used by any other library quiz or by a bundled
+// quiz. Caller must hold l.mu.
+func (l *Library) checkTitle(title, id string) error {
+	if e := l.catalog.Current().Entry(title); e != nil && e.File != SourceName {
+		return ErrTitleTaken
+	}
+	all, err := l.store.List()
+	if err != nil {
+		return err
+	}
+	for _, q := range all {
+		if q.ID != id && q.Quiz.Title == title {
+			return ErrTitleTaken
+		}
+	}
+	return nil
+}
+
+// OwnedBy reports whether token is the one the quiz was created with.
+func (q *Quiz) OwnedBy(token string) bool {
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(q.OwnerHash)) == 1
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,69 @@
+package library
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"time"
+
+	jsonstore "server/jsonstore"
+	trivia "server/trivia"
+)
+
+// ErrNotFound is returned by a Store when no quiz has the requested ID.
+var ErrNotFound = errors.New("quiz not found")
+
+// Quiz is a user-authored quiz along with who owns it and whether it can be played.
+type Quiz struct {
+	ID        string      `json:"id"`
+	OwnerHash string      `json:"ownerHash"` // sha256 of the creator's token; never sent to clients
+	Published bool        `json:"published"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+	Quiz      trivia.Quiz `json:"quiz"`
+}
+
+// Store persists library quizzes. Implementations must be safe for concurrent use.
+type Store interface {
+	List() ([]*Quiz, error)
+	Get(id string) (*Quiz, error)
+	Put(q *Quiz) error // creates or replaces the quiz with q.ID
+	Delete(id string) error
+}
+
+var validID = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// FileStore keeps each quiz as <id>.json in a directory.
+type FileStore struct {
+	files *jsonstore.Dir[Quiz]
+}
+
+// NewFileStore returns a store in dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	files, err := jsonstore.New[Quiz](dir, validID, ErrNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{files: files}, nil
+}
+
+func (s *FileStore) List() ([]*Quiz, error) {
+	quizzes, err := s.files.List()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(quizzes, func(a, b *Quiz) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return quizzes, nil
+}
+
+func (s *FileStore) Get(id string) (*Quiz, error) {
+	return s.files.Get(id)
+}
+
+func (s *FileStore) Put(q *Quiz) error {
+	return s.files.Put(q.ID, q)
+}
+
+func (s *FileStore) Delete(id string) error {
+	return s.files.Delete(id)
+}
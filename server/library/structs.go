@@ -0,0 +1,36 @@
+package library
+
+import (
+	"time"
+
+	trivia "server/trivia"
+)
+
+// QuizRequest is the JSON body for creating or updating a library quiz.
+// It has the same fields as a quiz in a versioned trivia file, plus Published.
+type QuizRequest struct {
+	trivia.Quiz
+	Published bool `json:"published"`
+}
+
+// QuizInfo describes a library quiz without its answers.
+type QuizInfo struct {
+	trivia.QuizSummary
+	ID        string    `json:"id"`
+	Published bool      `json:"published"`
+	Owned     bool      `json:"owned"` // whether the caller's token owns this quiz
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// QuizResponse is a library quiz as its owner sees it, answers included.
+type QuizResponse struct {
+	QuizInfo
+	Quiz       trivia.Quiz `json:"quiz"`
+	OwnerToken string      `json:"ownerToken,omitempty"` // only set when the quiz is created
+}
+
+// ErrorResponse is the JSON response for errors.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
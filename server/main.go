@@ -5,10 +5,14 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 
+	game "server/game"
 	gameinit "server/game-init"
+	history "server/history"
+	library "server/library"
 	state "server/state"
 	trivia "server/trivia"
 )
@@ -41,19 +45,57 @@ func cors(next http.Handler) http.Handler {
 func main() {
 	fmt.Println("Welcome to Sporcle!")
 
-	globalState := state.NewGlobalState()
+	catalog, err := trivia.OpenLiveCatalog(state.TriviaBasePath)
+	if err != nil {
+		log.Fatalf("loading trivia: %v", err)
+	}
+	for file, reason := range catalog.Current().Rejected {
+		log.Printf("skipping trivia file %s: %v", file, reason)
+	}
+	for _, dup := range catalog.Current().Duplicates {
+		log.Printf("skipping %s", dup)
+	}
+	// pick up quizzes added to the trivia directory without a restart
+	go catalog.Watch(trivia.DefaultPollInterval, nil)
+
+	store, err := library.NewFileStore(library.DefaultPath)
+	if err != nil {
+		log.Fatalf("opening quiz library: %v", err)
+	}
+	lib, err := library.New(store, catalog)
+	if err != nil {
+		log.Fatalf("loading quiz library: %v", err)
+	}
+
+	matches, err := history.NewFileStore(history.DefaultPath)
+	if err != nil {
+		log.Fatalf("opening game history: %v", err)
+	}
+
+	globalState := state.NewGlobalStateWithCatalog(catalog)
+	globalState.SetHistory(matches)
 	mux := http.NewServeMux()
 	gameinit.RegisterRoutes(mux, globalState)
-	trivia.RegisterRoutes(mux)
+	trivia.RegisterRoutes(mux, catalog)
+	library.RegisterRoutes(mux, lib)
+	// delivery metrics from the game package only; expvar.Handler would also publish cmdline and memstats
+	mux.Handle("/debug/vars", game.MetricsHandler())
 
 	handler := cors(mux)
-	err := godotenv.Load()
+	err = godotenv.Load()
 	if err != nil {
 		log.Println("Error loading .env file, assuming environment variables are set externally")
 		return
 	}
 
 	listen := os.Getenv("SERVER_BASE_URL")
+	if retention := os.Getenv("RESULTS_RETENTION"); retention != "" {
+		d, err := time.ParseDuration(retention)
+		if err != nil {
+			log.Fatalf("RESULTS_RETENTION: %v", err)
+		}
+		globalState.SetResultsRetention(d)
+	}
 
 	if err := http.ListenAndServe(listen, handler); err != nil {
 		log.Fatal(err)
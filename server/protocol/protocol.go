@@ -0,0 +1,302 @@
+/*
+Package protocol defines the messages a game sends and receives over its
+WebSocket.
+
+A client picks a version with ?v= when it connects to /ws. Without one it
+gets LegacyVersion, the format the original client reads: every event is a
+game.GameEvent with capitalized Go field names, fields added since then are
+left out when empty, and each change to the board is followed by a Board
+event carrying the whole board. Newer event types, such as Claimed and
+Guess, are sent too; the original client ignores them. From version 2 every
+message is a JSON object whose "type" says which of the structs below it
+is, with lower-case fields and errors identified by an ErrorCode.
+
+Schema describes the messages as JSON Schema, so clients can generate
+their types from it.
+*/
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	LegacyVersion = 1 // game.GameEvent plus whole-board Board events; what clients that don't ask get
+	Version       = 2 // the newest version this server speaks
+)
+
+// A MessageType is the "type" of a message.
+type MessageType string
+
+// Sent by the server.
+const (
+	TypeWelcome     MessageType = "welcome"     // the connection joined the game
+	TypeError       MessageType = "error"       // the connection or one of its requests was rejected
+	TypeTime        MessageType = "time"        // seconds left in the lobby or game
+	TypePlayers     MessageType = "players"     // who is in the game
+	TypeStart       MessageType = "start"       // the lobby is over and squares can be claimed
+	TypePhase       MessageType = "phase"       // the game moved to a new phase
+	TypeClaimed     MessageType = "claimed"     // one square was claimed
+	TypeStolen      MessageType = "stolen"      // in steal mode: a claimed square changed hands
+	TypeSnapshot    MessageType = "snapshot"    // the whole game, on joining and on resync
+	TypeLeaderboard MessageType = "leaderboard" // the game is over
+	TypeGuess       MessageType = "guess"       // what became of one of the client's claims
+)
+
+// Sent by the client.
+const (
+	TypeClaim    MessageType = "claim"    // a guess at a square
+	TypeResync   MessageType = "resync"   // a request for a new snapshot
+	TypeGameOver MessageType = "gameOver" // the client has shown the leaderboard and is leaving
+	TypePickTeam MessageType = "pickTeam" // in a team game's lobby: move to another team
+)
+
+// An ErrorCode says why something was rejected. Messages alongside it are
+// for people; clients should act on the code.
+type ErrorCode string
+
+const (
+	ErrMissingParams      ErrorCode = "missing_params"      // /ws needs game and user
+	ErrGameNotFound       ErrorCode = "game_not_found"      // no game has this code
+	ErrUsernameTaken      ErrorCode = "username_taken"      // someone in the game has this name
+	ErrGameStarted        ErrorCode = "game_started"        // new players can't join a started game
+	ErrGameClosed         ErrorCode = "game_closed"         // the game is over
+	ErrUnsupportedVersion ErrorCode = "unsupported_version" // ?v= is not a version this server speaks
+	ErrWrongPlayer        ErrorCode = "wrong_player"        // the request names another player or game
+	ErrNotAccepted        ErrorCode = "not_accepted"        // the request isn't allowed in the current phase
+	ErrBadMessage         ErrorCode = "bad_message"         // the message has an unknown type
+	ErrMatchNotFound      ErrorCode = "match_not_found"     // no recorded match has this ID
+	ErrInvalidParams      ErrorCode = "invalid_params"      // a query parameter has a value the server can't use
+	ErrUnknownTeam        ErrorCode = "unknown_team"        // the game has no team by that name
+)
+
+// ErrorCodes lists every ErrorCode.
+var ErrorCodes = []ErrorCode{
+	ErrMissingParams, ErrGameNotFound, ErrUsernameTaken, ErrGameStarted, ErrGameClosed,
+	ErrUnsupportedVersion, ErrWrongPlayer, ErrNotAccepted, ErrBadMessage, ErrMatchNotFound,
+	ErrInvalidParams, ErrUnknownTeam,
+}
+
+// A GuessOutcome is what became of a claim.
+type GuessOutcome string
+
+const (
+	GuessClaimed        GuessOutcome = "claimed"         // the square is the player's
+	GuessAlreadyClaimed GuessOutcome = "already_claimed" // someone, maybe the player, got there first
+	GuessNotOnBoard     GuessOutcome = "not_on_board"    // the guess matches no square
+	GuessNotRunning     GuessOutcome = "not_running"     // squares can't be claimed in this phase
+	GuessRateLimited    GuessOutcome = "rate_limited"    // the player is guessing too fast; it was ignored
+	GuessStolen         GuessOutcome = "stolen"          // in steal mode: the player took the square from its owner
+	GuessCooldown       GuessOutcome = "cooldown"        // in steal mode: the square was taken too recently to steal
+	GuessLocked         GuessOutcome = "locked"          // in steal mode: the square has been stolen as often as it can be
+)
+
+// GuessOutcomes lists every GuessOutcome.
+var GuessOutcomes = []GuessOutcome{
+	GuessClaimed, GuessAlreadyClaimed, GuessNotOnBoard, GuessNotRunning, GuessRateLimited,
+	GuessStolen, GuessCooldown, GuessLocked,
+}
+
+var errUnsupported = errors.New("unsupported protocol version")
+
+/*
+Negotiate returns the version to speak with a client that asked for
+requested, the value of ?v=. A client may ask for a version newer than
+this server's and gets Version; one that asks for nothing gets
+LegacyVersion.
+*/
+func Negotiate(requested string) (int, error) {
+	if requested == "" {
+		return LegacyVersion, nil
+	}
+	v, err := strconv.Atoi(requested)
+	if err != nil || v < LegacyVersion {
+		return 0, fmt.Errorf("%w: %q", errUnsupported, requested)
+	}
+	return min(v, Version), nil
+}
+
+// Player is a player as other players see them.
+type Player struct {
+	Username  string `json:"username"`
+	Color     string `json:"color"`
+	Connected bool   `json:"connected"`
+	Team      string `json:"team,omitempty"` // in team games
+}
+
+// Team is a side in a team game. Its members' squares are shown in its Color.
+type Team struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// LeaderboardEntry is one player's final result. Tied players share a Rank.
+type LeaderboardEntry struct {
+	Rank     int      `json:"rank"`
+	Username string   `json:"username"`
+	Color    string   `json:"color"`
+	Team     string   `json:"team,omitempty"` // in team games
+	Correct  int      `json:"correct"`
+	Items    []string `json:"items"` // the squares they hold, in the order they were claimed
+}
+
+// TeamStanding is one team's final result, with what each member contributed.
+type TeamStanding struct {
+	Rank    int                `json:"rank"`
+	Name    string             `json:"name"`
+	Color   string             `json:"color"`
+	Correct int                `json:"correct"`
+	Members []LeaderboardEntry `json:"members"`
+}
+
+// CoopResult is how a cooperative game went: it succeeds if every square
+// is found before time runs out.
+type CoopResult struct {
+	Success    bool     `json:"success"`
+	Found      int      `json:"found"`
+	Total      int      `json:"total"`
+	Completion float64  `json:"completion"` // percent of squares found
+	Missed     []string `json:"missed"`
+}
+
+// Welcome is the first message on a connection that joined a game. Token
+// reconnects as the same player with &token=.
+type Welcome struct {
+	Type    MessageType `json:"type"`
+	Version int         `json:"version"`
+	Title   string      `json:"title"`
+	Token   string      `json:"token"`
+}
+
+// Error rejects the connection, if it is the first message, or one request.
+type Error struct {
+	Type    MessageType `json:"type"`
+	Code    ErrorCode   `json:"code"`
+	Message string      `json:"message"`
+}
+
+type Time struct {
+	Type     MessageType `json:"type"`
+	TimeLeft int         `json:"timeLeft"`
+}
+
+type Players struct {
+	Type    MessageType `json:"type"`
+	Players []Player    `json:"players"` // sorted by username
+}
+
+type Start struct {
+	Type MessageType `json:"type"`
+}
+
+type Phase struct {
+	Type  MessageType `json:"type"`
+	Phase string      `json:"phase"` // lobby, countdown, running, paused, finished or closed
+}
+
+// Stolen is sent in steal mode when a player takes a square from another.
+// It is numbered along with Claimed.
+type Stolen struct {
+	Type   MessageType `json:"type"`
+	Seq    int         `json:"seq"`
+	Item   string      `json:"item"`
+	Player string      `json:"player"` // username of the new owner
+	From   string      `json:"from"`   // username of the old owner
+	Team   string      `json:"team,omitempty"`
+}
+
+// Claimed is sent for each square claimed. Seq counts claims, and steals,
+// from 1; a client that sees a gap should send Resync.
+type Claimed struct {
+	Type   MessageType `json:"type"`
+	Seq    int         `json:"seq"`
+	Item   string      `json:"item"`
+	Player string      `json:"player"`         // username
+	Team   string      `json:"team,omitempty"` // the player's team, in team games
+}
+
+// Snapshot is the whole game. The next Claimed or Stolen has Seq+1.
+type Snapshot struct {
+	Type        MessageType        `json:"type"`
+	Seq         int                `json:"seq"`
+	Phase       string             `json:"phase"`
+	Started     bool               `json:"started"`
+	TimeLeft    int                `json:"timeLeft"`
+	Board       map[string]string  `json:"board"` // item -> username of who claimed it, "" if unclaimed
+	Players     []Player           `json:"players"`
+	Teams       []Team             `json:"teams,omitempty"`           // in team games
+	Leaderboard []LeaderboardEntry `json:"leaderboard,omitempty"`     // once the game has finished
+	Unfound     []string           `json:"unfound,omitempty"`         // once the game has finished: squares nobody claimed
+	TeamBoard   []TeamStanding     `json:"teamLeaderboard,omitempty"` // once a team game has finished
+	Coop        *CoopResult        `json:"coop,omitempty"`            // once a cooperative game has finished
+}
+
+// Leaderboard is sent to everyone when the game ends. Entries has every
+// player who played, best first.
+type Leaderboard struct {
+	Type    MessageType        `json:"type"`
+	Entries []LeaderboardEntry `json:"entries"`
+	Unfound []string           `json:"unfound"`         // squares nobody claimed
+	Teams   []TeamStanding     `json:"teams,omitempty"` // in team games: every team, best first
+	Coop    *CoopResult        `json:"coop,omitempty"`  // in cooperative games
+}
+
+// Guess answers one Claim, and only goes to the player who sent it.
+type Guess struct {
+	Type      MessageType  `json:"type"`
+	ID        string       `json:"id,omitempty"` // the Claim's ID
+	Outcome   GuessOutcome `json:"outcome"`
+	Item      string       `json:"item,omitempty"`      // the square the guess matched, if any
+	ClaimedBy string       `json:"claimedBy,omitempty"` // username of who held the square, unless the guess claimed it
+}
+
+// Claim guesses at a square. ID is chosen by the client and echoed in the Guess that answers it.
+type Claim struct {
+	Type MessageType `json:"type"`
+	ID   string      `json:"id,omitempty"`
+	Item string      `json:"item"`
+}
+
+// PickTeam moves the player to another team. It is only accepted before the game starts.
+type PickTeam struct {
+	Type MessageType `json:"type"`
+	Team string      `json:"team"`
+}
+
+type Resync struct {
+	Type MessageType `json:"type"`
+}
+
+type GameOver struct {
+	Type MessageType `json:"type"`
+}
+
+// ServerMessages and ClientMessages map each message type to its struct.
+var (
+	ServerMessages = map[MessageType]any{
+		TypeWelcome:     Welcome{},
+		TypeError:       Error{},
+		TypeTime:        Time{},
+		TypePlayers:     Players{},
+		TypeStart:       Start{},
+		TypePhase:       Phase{},
+		TypeClaimed:     Claimed{},
+		TypeStolen:      Stolen{},
+		TypeSnapshot:    Snapshot{},
+		TypeLeaderboard: Leaderboard{},
+		TypeGuess:       Guess{},
+	}
+	ClientMessages = map[MessageType]any{
+		TypeClaim:    Claim{},
+		TypeResync:   Resync{},
+		TypeGameOver: GameOver{},
+		TypePickTeam: PickTeam{},
+	}
+)
+
+// NewError returns an Error message.
+func NewError(code ErrorCode, message string) Error {
+	return Error{Type: TypeError, Code: code, Message: message}
+}
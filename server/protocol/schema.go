@@ -0,0 +1,105 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var messageTypeType = reflect.TypeFor[MessageType]()
+
+// enums are the string types whose values are listed in the schema.
+var enums = map[reflect.Type]any{
+	reflect.TypeFor[ErrorCode]():    ErrorCodes,
+	reflect.TypeFor[GuessOutcome](): GuessOutcomes,
+}
+
+/*
+Schema returns a JSON Schema for the current Version. Every message
+struct is under $defs, as are ServerMessage and ClientMessage, which
+accept any one message the server or client may send. Each message's
+"type" is a const, so code generators can discriminate on it.
+
+	go run ./cmd/protocol-schema > protocol/schema.json
+*/
+func Schema() ([]byte, error) {
+	defs := map[string]any{}
+	for t, values := range enums {
+		defs[t.Name()] = map[string]any{"type": "string", "enum": values}
+	}
+	root := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "Sporcle WebSocket protocol",
+		"version": Version,
+		"$defs":   defs,
+	}
+	defs["ServerMessage"] = oneOf(defs, ServerMessages)
+	defs["ClientMessage"] = oneOf(defs, ClientMessages)
+	return json.MarshalIndent(root, "", "  ")
+}
+
+func oneOf(defs map[string]any, messages map[MessageType]any) map[string]any {
+	var refs []any
+	for _, t := range slices.Sorted(maps.Keys(messages)) {
+		refs = append(refs, define(defs, reflect.TypeOf(messages[t]), t))
+	}
+	return map[string]any{"oneOf": refs}
+}
+
+// define adds the struct t to defs and returns a reference to it. msgType is
+// the const its "type" field must hold, if it is a message.
+func define(defs map[string]any, t reflect.Type, msgType MessageType) map[string]any {
+	ref := map[string]any{"$ref": "#/$defs/" + t.Name()}
+	if _, done := defs[t.Name()]; done {
+		return ref
+	}
+	properties := map[string]any{}
+	required := []string{}
+	def := map[string]any{"type": "object", "properties": properties}
+	defs[t.Name()] = def
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if f.Type == messageTypeType {
+			properties[name] = map[string]any{"const": msgType}
+		} else {
+			properties[name] = fieldSchema(defs, f.Type)
+		}
+		if opts != "omitempty" {
+			required = append(required, name)
+		}
+	}
+	def["required"] = required
+	return ref
+}
+
+func fieldSchema(defs map[string]any, t reflect.Type) map[string]any {
+	if _, ok := enums[t]; ok {
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Int:
+		return map[string]any{"type": "integer"}
+	case reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Pointer:
+		return fieldSchema(defs, t.Elem())
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": fieldSchema(defs, t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": fieldSchema(defs, t.Elem())}
+	case reflect.Struct:
+		return define(defs, t, "")
+	}
+	panic(fmt.Sprintf("protocol: no schema for %s", t))
+}
@@ -0,0 +1,87 @@
+package state
+
+import (
+	"log"
+	"time"
+
+	game "server/game"
+	history "server/history"
+)
+
+// DefaultResultsRetention is how long a finished game's results are kept
+// after the game closes.
+const DefaultResultsRetention = 7 * 24 * time.Hour
+
+type retainedResults struct {
+	results game.Results
+	expires time.Time
+}
+
+// SetResultsRetention sets how long results are kept for games that close
+// from now on. 0 keeps them only while the game is open.
+func (s *GlobalState) SetResultsRetention(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retention = d
+}
+
+/*
+GetResults returns the results of the game with the given code, whether it
+is still lingering or closed within the retention period. After that they
+come from the history store, if there is one. ok is false if there is no
+such game or it has not finished.
+*/
+func (s *GlobalState) GetResults(code string) (results game.Results, ok bool) {
+	if m := s.GetGame(code); m != nil {
+		return m.Results()
+	}
+	s.mu.Lock()
+	s.pruneResults()
+	r, ok := s.results[code]
+	store := s.history
+	s.mu.Unlock()
+	if ok {
+		return r.results, true
+	}
+	return recordedResults(store, code)
+}
+
+// recordedResults returns the results of the last match store recorded for
+// code. Codes are reused, so earlier matches with it are older games.
+func recordedResults(store history.Store, code string) (game.Results, bool) {
+	if store == nil {
+		return game.Results{}, false
+	}
+	matches, err := store.List()
+	if err != nil {
+		log.Printf("looking up results for %s: %v", code, err)
+		return game.Results{}, false
+	}
+	for i := len(matches) - 1; i >= 0; i-- {
+		if matches[i].Code == code {
+			return matches[i].Results, true
+		}
+	}
+	return game.Results{}, false
+}
+
+// retainResults keeps m's results, if it finished, once it is removed.
+func (s *GlobalState) retainResults(m *game.Manager) {
+	results, ok := m.Results()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneResults()
+	if ok && s.retention > 0 {
+		s.results[m.Code] = retainedResults{results: results, expires: s.clock.Now().Add(s.retention)}
+	}
+}
+
+// pruneResults drops expired results. Caller holds the lock.
+func (s *GlobalState) pruneResults() {
+	now := s.clock.Now()
+	for code, r := range s.results {
+		if !now.Before(r.expires) {
+			delete(s.results, code)
+		}
+	}
+}
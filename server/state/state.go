@@ -1,32 +1,90 @@
 package state
 
 import (
-	"encoding/json"
+	"log"
 	"math/rand"
-	"os"
-	"path/filepath"
 	"sync"
+	"time"
 
+	clock "server/clock"
 	game "server/game"
+	history "server/history"
+	trivia "server/trivia"
 )
 
 const codeChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
 // GlobalState holds games and usernames. Use getters/setters for concurrent access.
 type GlobalState struct {
-	games map[string]*game.Manager
-	mu    sync.RWMutex
+	games     map[string]*game.Manager
+	results   map[string]retainedResults // finished games that have been removed, by code
+	retention time.Duration              // how long results outlive their game
+	history   history.Store              // where finished games are recorded; nil keeps no history
+	catalog   *trivia.LiveCatalog        // quizzes games can be created from
+	clock     clock.Clock                // what new games keep time with
+	mu        sync.RWMutex
 }
 
-// NewGlobalState returns an initialized GlobalState.
+// NewGlobalState returns an initialized GlobalState with a catalog loaded
+// from TriviaBasePath.
 func NewGlobalState() *GlobalState {
+	catalog, err := trivia.OpenLiveCatalog(TriviaBasePath)
+	if err != nil {
+		log.Printf("loading trivia from %s: %v", TriviaBasePath, err)
+	}
+	return NewGlobalStateWithCatalog(catalog)
+}
+
+// NewGlobalStateWithCatalog returns an initialized GlobalState that creates
+// games from whatever catalog is current when the game is created.
+func NewGlobalStateWithCatalog(catalog *trivia.LiveCatalog) *GlobalState {
 	return &GlobalState{
-		games: make(map[string]*game.Manager),
+		games:     make(map[string]*game.Manager),
+		results:   make(map[string]retainedResults),
+		retention: DefaultResultsRetention,
+		catalog:   catalog,
+		clock:     clock.Real,
 	}
 }
 
+// SetClock makes games created from now on keep time with c. Tests use a
+// clock.Fake to run games without waiting.
+func (s *GlobalState) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// SetHistory makes games created from now on record themselves in store
+// when they finish.
+func (s *GlobalState) SetHistory(store history.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = store
+}
+
+// History returns where finished games are recorded, or nil.
+func (s *GlobalState) History() history.Store {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.history
+}
+
+// Clock returns what games keep time with.
+func (s *GlobalState) Clock() clock.Clock {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clock
+}
+
+// Catalog returns the current trivia catalog.
+func (s *GlobalState) Catalog() *trivia.Catalog {
+	return s.catalog.Current()
+}
+
 // generateCode returns a random code of 6 capitalized letters/numbers
-// that is not already a key in games.
+// that is not already a key in games or results, so a results URL always
+// names the same game.
 // Assumes caller has the lock for the global state.
 func (s *GlobalState) generateCode() string {
 	for {
@@ -35,7 +93,7 @@ func (s *GlobalState) generateCode() string {
 			b[i] = codeChars[rand.Intn(len(codeChars))]
 		}
 		code := string(b)
-		if s.games[code] == nil {
+		if _, retained := s.results[code]; s.games[code] == nil && !retained {
 			return code
 		}
 	}
@@ -55,26 +113,93 @@ func (s *GlobalState) SetGame(code string, m *game.Manager) {
 	s.games[code] = m
 }
 
-// RemoveGame removes the Manager for the given code.
+// RemoveGame removes the Manager for the given code. If the game finished,
+// its results stay available from GetResults for the retention period.
 func (s *GlobalState) RemoveGame(code string) {
+	if m := s.GetGame(code); m != nil {
+		s.retainResults(m)
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.games, code)
 }
 
-// Create checks code and title, then creates a new Manager with board keys from trivia.
-// Returns nil if code already exists or title is not found in trivia.
+// Server-wide defaults used when neither the host nor the quiz picks a time.
+const (
+	DefaultLobbyTime = 60
+	DefaultGameTime  = 180
+)
+
+// GameOptions are the host's settings for a new game. Zero values fall back
+// to the quiz's defaults, then to the server's.
+type GameOptions struct {
+	Title      string
+	LobbyTime  int
+	GameTime   int
+	LingerTime *int // seconds a finished game stays open; nil means game.DefaultLingerTime
+	GuessRate  *int // claims a player may make per second, 0 for no limit; nil means game.DefaultGuessRate
+	Strictness game.Strictness
+	TieBreaker game.TieBreaker // "" means game.TieBreakShared
+	Teams      []string        // team names for a team game, checked with game.ValidateTeams; nil for every player for themselves
+	Mode       game.Mode       // "" means game.ModeCompetitive
+	// Steal mode's rules, checked with game.ValidateSteal; ignored unless Steal is set.
+	Steal         bool
+	StealCooldown int
+	MaxSteals     int
+	Answers       []game.Answer // a custom quiz; when set, Title is not looked up in the catalog
+}
+
+// Create creates a game for title with default options. See CreateGame.
 func (state *GlobalState) Create(title string, lobbyTime, gameTime int) *game.Manager {
+	return state.CreateGame(GameOptions{Title: title, LobbyTime: lobbyTime, GameTime: gameTime})
+}
+
+// CreateGame looks up the title in the catalog, then creates a new Manager with its answers.
+// Returns nil if the title is not in the catalog. Custom answers in opts are assumed
+// to have been checked with trivia.ValidateCustomQuiz.
+func (state *GlobalState) CreateGame(opts GameOptions) *game.Manager {
 	state.mu.Lock()
-	items := loadTriviaItems(title)
-	if items == nil {
-		state.mu.Unlock()
-		return nil
+	answers := opts.Answers
+	quiz := &trivia.Quiz{} // custom quizzes have no defaults of their own
+	if answers == nil {
+		// the Manager copies the answers, so a later catalog reload won't affect this game
+		if quiz = state.catalog.Current().Quiz(opts.Title); quiz == nil {
+			state.mu.Unlock()
+			return nil
+		}
+		answers = quiz.GameAnswers()
 	}
+	lobbyTime := firstNonZero(opts.LobbyTime, quiz.LobbyTime, DefaultLobbyTime)
+	gameTime := firstNonZero(opts.GameTime, quiz.GameTime, DefaultGameTime)
+	strictness := firstNonZero(opts.Strictness, quiz.Strictness, game.StrictnessNormal)
 	code := state.generateCode()
-	m := game.NewManager(title, code, lobbyTime, gameTime)
-	for _, item := range items {
-		m.Board[item] = nil
+	settings := game.DefaultOptions()
+	if opts.LingerTime != nil {
+		settings.LingerTime = *opts.LingerTime
+	}
+	if opts.GuessRate != nil {
+		settings.GuessRate = *opts.GuessRate
+	}
+	if opts.TieBreaker != "" {
+		settings.TieBreaker = opts.TieBreaker
+	}
+	if opts.Mode != "" {
+		settings.Mode = opts.Mode
+	}
+	if opts.Steal {
+		settings.Steal, settings.StealCooldown, settings.MaxSteals = true, opts.StealCooldown, opts.MaxSteals
+	}
+	if store := state.history; store != nil {
+		settings.OnFinish = func(r game.Results) {
+			if err := store.Put(history.NewMatch(r)); err != nil {
+				log.Printf("recording game %s: %v", r.Code, err)
+			}
+		}
+	}
+	m := game.NewManager(opts.Title, code, lobbyTime, gameTime, state.clock, settings)
+	m.SetAnswers(answers, strictness)
+	if len(opts.Teams) > 0 {
+		m.SetTeams(opts.Teams)
 	}
 	state.games[code] = m
 	state.mu.Unlock()
@@ -96,30 +221,16 @@ func (state *GlobalState) CanJoin(code, username string) (bool, bool) {
 }
 
 // TriviaBasePath is the path to the trivia directory (relative to server when run from server/).
+// NewGlobalState loads its catalog from here.
 var TriviaBasePath = "../trivia"
 
-// loadTriviaItems finds title in any trivia/*.json and returns the list of items, or nil.
-func loadTriviaItems(title string) []string {
-	entries, err := os.ReadDir(TriviaBasePath)
-	if err != nil {
-		return nil
-	}
-	for _, e := range entries {
-		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
-			continue
-		}
-		path := filepath.Join(TriviaBasePath, e.Name())
-		data, err := os.ReadFile(path)
-		if err != nil {
-			continue
-		}
-		var obj map[string][]string
-		if json.Unmarshal(data, &obj) != nil {
-			continue
-		}
-		if items, ok := obj[title]; ok {
-			return items
+// firstNonZero returns the first of vals that is not the zero value.
+func firstNonZero[T comparable](vals ...T) T {
+	var zero T
+	for _, v := range vals {
+		if v != zero {
+			return v
 		}
 	}
-	return nil
+	return zero
 }
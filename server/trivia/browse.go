@@ -0,0 +1,241 @@
+package trivia
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	game "server/game"
+)
+
+// Paging limits for /trivia/quizzes.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+	MaxPage         = 100000 // keeps (page-1)*pageSize far from overflowing
+)
+
+// QuizSummary describes a quiz without revealing its answers.
+type QuizSummary struct {
+	Title       string          `json:"title"`
+	Category    string          `json:"category"` // trivia file name without ".json"
+	Description string          `json:"description,omitempty"`
+	Difficulty  string          `json:"difficulty,omitempty"`
+	Author      string          `json:"author,omitempty"`
+	Tags        []string        `json:"tags"`
+	LobbyTime   int             `json:"lobbyTime,omitempty"`
+	GameTime    int             `json:"gameTime,omitempty"`
+	Strictness  game.Strictness `json:"strictness,omitempty"`
+	AnswerCount int             `json:"answerCount"`
+}
+
+// GroupSummary is how many answers fall in one group, e.g. "NFC West": 4.
+type GroupSummary struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// QuizDetail is the response for /trivia/quizzes/{title}.
+type QuizDetail struct {
+	QuizSummary
+	Groups []GroupSummary `json:"groups"`
+}
+
+// QuizListResponse is one page of /trivia/quizzes results.
+type QuizListResponse struct {
+	Quizzes  []QuizSummary `json:"quizzes"`
+	Total    int           `json:"total"` // matches across all pages
+	Page     int           `json:"page"`
+	PageSize int           `json:"pageSize"`
+}
+
+// ErrorResponse is the JSON response for errors.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Summary returns the public description of the entry.
+func (e *Entry) Summary() QuizSummary {
+	tags := e.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return QuizSummary{
+		Title:       e.Title,
+		Category:    strings.TrimSuffix(e.File, ".json"),
+		Description: e.Description,
+		Difficulty:  e.Difficulty,
+		Author:      e.Author,
+		Tags:        tags,
+		LobbyTime:   e.LobbyTime,
+		GameTime:    e.GameTime,
+		Strictness:  e.Strictness,
+		AnswerCount: len(e.Answers),
+	}
+}
+
+// quizSorts maps the `sort` query parameter to a comparison on summaries.
+// Ties are always broken by title, ascending.
+var quizSorts = map[string]func(a, b QuizSummary) int{
+	"title":    func(a, b QuizSummary) int { return cmp.Compare(a.Title, b.Title) },
+	"category": func(a, b QuizSummary) int { return cmp.Compare(a.Category, b.Category) },
+	"answers":  func(a, b QuizSummary) int { return cmp.Compare(a.AnswerCount, b.AnswerCount) },
+	"difficulty": func(a, b QuizSummary) int {
+		return cmp.Compare(difficultyRank(a.Difficulty), difficultyRank(b.Difficulty))
+	},
+}
+
+// difficultyRank orders quizzes easy to hard, with unrated quizzes last.
+func difficultyRank(d string) int {
+	if i := slices.Index(Difficulties, d); i >= 0 {
+		return i
+	}
+	return len(Difficulties)
+}
+
+/*
+listQuizzesHandler returns a page of quiz summaries. Query parameters:
+
+	q          text to find in the title, description, author, category or tags
+	tag        only quizzes with this tag; may be repeated, all must match
+	difficulty only quizzes of this difficulty
+	category   only quizzes from this trivia file
+	sort       title (default), category, answers or difficulty; prefix "-" to reverse
+	page       1-based page number (default 1)
+	pageSize   results per page (default 20, max 100)
+*/
+func listQuizzesHandler(catalog *Catalog, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := r.URL.Query()
+
+	sortKey := cmp.Or(q.Get("sort"), "title")
+	desc := strings.HasPrefix(sortKey, "-")
+	compare, ok := quizSorts[strings.TrimPrefix(sortKey, "-")]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "sort must be one of title, category, answers, difficulty")
+		return
+	}
+	page, err := positiveParam(q.Get("page"), 1)
+	if err != nil || page > MaxPage {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("page must be an integer from 1 to %d", MaxPage))
+		return
+	}
+	pageSize, err := positiveParam(q.Get("pageSize"), DefaultPageSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "pageSize must be a positive integer")
+		return
+	}
+	pageSize = min(pageSize, MaxPageSize)
+
+	search := game.Normalize(q.Get("q"))
+	tags := q["tag"]
+	difficulty := q.Get("difficulty")
+	category := strings.TrimSuffix(q.Get("category"), ".json")
+
+	matches := []QuizSummary{}
+	for _, e := range catalog.Entries() {
+		s := e.Summary()
+		if difficulty != "" && s.Difficulty != difficulty {
+			continue
+		}
+		if category != "" && s.Category != category {
+			continue
+		}
+		if !hasAllTags(s.Tags, tags) {
+			continue
+		}
+		if search != "" && !strings.Contains(searchText(s), search) {
+			continue
+		}
+		matches = append(matches, s)
+	}
+	slices.SortFunc(matches, func(a, b QuizSummary) int {
+		c := compare(a, b)
+		if desc {
+			c = -c
+		}
+		return cmp.Or(c, cmp.Compare(a.Title, b.Title))
+	})
+
+	start := min((page-1)*pageSize, len(matches))
+	end := min(start+pageSize, len(matches))
+	writeJSON(w, http.StatusOK, QuizListResponse{
+		Quizzes:  matches[start:end],
+		Total:    len(matches),
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// getQuizHandler returns the metadata for one quiz. Answers are never included.
+func getQuizHandler(catalog *Catalog, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	entry := catalog.Entry(r.PathValue("title"))
+	if entry == nil {
+		writeError(w, http.StatusNotFound, "no quiz with this title")
+		return
+	}
+
+	detail := QuizDetail{QuizSummary: entry.Summary(), Groups: []GroupSummary{}}
+	counts := make(map[string]int)
+	for _, a := range entry.Answers {
+		if a.Group == "" {
+			continue
+		}
+		if counts[a.Group] == 0 {
+			detail.Groups = append(detail.Groups, GroupSummary{Name: a.Group})
+		}
+		counts[a.Group]++
+	}
+	for i := range detail.Groups {
+		detail.Groups[i].Count = counts[detail.Groups[i].Name]
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// searchText is everything free-text search looks at, normalized.
+func searchText(s QuizSummary) string {
+	fields := append([]string{s.Title, s.Category, s.Description, s.Author}, s.Tags...)
+	return game.Normalize(strings.Join(fields, " | "))
+}
+
+func hasAllTags(have, want []string) bool {
+	for _, w := range want {
+		if !slices.ContainsFunc(have, func(h string) bool { return strings.EqualFold(h, w) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// positiveParam parses an optional positive integer query parameter.
+func positiveParam(v string, fallback int) (int, error) {
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, strconv.ErrRange
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, ErrorResponse{Error: msg})
+}
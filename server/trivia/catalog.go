@@ -0,0 +1,166 @@
+package trivia
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Entry is a quiz in the catalog along with the file it was loaded from.
+type Entry struct {
+	Quiz
+	File string `json:"file"`
+}
+
+// Duplicate records a quiz title that appears in more than one file. The
+// quiz from File was ignored in favor of the one from Kept.
+type Duplicate struct {
+	Title string
+	File  string
+	Kept  string
+}
+
+func (d Duplicate) String() string {
+	return fmt.Sprintf("quiz %q in %s duplicates the one in %s", d.Title, d.File, d.Kept)
+}
+
+/*
+A Catalog is an in-memory index of every quiz in the trivia directory,
+by title, by file and by tag. It is built by LoadCatalog, plus any
+LiveCatalog sources, and is never modified once shared, so it is safe
+for concurrent use.
+*/
+type Catalog struct {
+	entries    []*Entry            // every quiz, ordered by file then position in file
+	byTitle    map[string]*Entry   // title -> quiz
+	byFile     map[string][]*Entry // file name -> quizzes in that file
+	byTag      map[string][]*Entry // lowercase tag -> quizzes with that tag
+	files      []string            // files that parsed, sorted
+	Rejected   map[string]error    // file name -> why it could not be parsed
+	Duplicates []Duplicate         // titles that were defined more than once
+}
+
+// NewCatalog returns an empty catalog.
+func NewCatalog() *Catalog {
+	return &Catalog{
+		byTitle:  make(map[string]*Entry),
+		byFile:   make(map[string][]*Entry),
+		byTag:    make(map[string][]*Entry),
+		Rejected: make(map[string]error),
+	}
+}
+
+// LoadCatalog parses every .json file in dir. Files that fail to parse are
+// recorded in Rejected rather than failing the whole load; an error is only
+// returned if dir itself can't be read.
+func LoadCatalog(dir string) (*Catalog, error) {
+	c := NewCatalog()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return c, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		quizzes, err := LoadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			c.Rejected[e.Name()] = err
+			continue
+		}
+		c.Add(e.Name(), quizzes)
+	}
+	return c, nil
+}
+
+// Add indexes the quizzes from file. It is only meant to be used while
+// building a catalog, before it is shared.
+func (c *Catalog) Add(file string, quizzes []Quiz) {
+	if _, seen := c.byFile[file]; !seen {
+		c.files = append(c.files, file)
+		slices.Sort(c.files)
+		c.byFile[file] = []*Entry{}
+	}
+	for _, q := range quizzes {
+		if existing, ok := c.byTitle[q.Title]; ok {
+			c.Duplicates = append(c.Duplicates, Duplicate{Title: q.Title, File: file, Kept: existing.File})
+			continue
+		}
+		entry := &Entry{Quiz: q, File: file}
+		c.entries = append(c.entries, entry)
+		c.byTitle[q.Title] = entry
+		c.byFile[file] = append(c.byFile[file], entry)
+		for _, tag := range q.Tags {
+			tag = strings.ToLower(tag)
+			c.byTag[tag] = append(c.byTag[tag], entry)
+		}
+	}
+}
+
+// clone returns a copy of c that can be added to without affecting c.
+func (c *Catalog) clone() *Catalog {
+	next := NewCatalog()
+	for _, file := range c.files {
+		next.Add(file, quizzesOf(c.byFile[file]))
+	}
+	maps.Copy(next.Rejected, c.Rejected)
+	next.Duplicates = slices.Clone(c.Duplicates)
+	return next
+}
+
+// Quiz returns the quiz with the given title, or nil.
+func (c *Catalog) Quiz(title string) *Quiz {
+	if entry, ok := c.byTitle[title]; ok {
+		return &entry.Quiz
+	}
+	return nil
+}
+
+// Entry returns the catalog entry for title, or nil.
+func (c *Catalog) Entry(title string) *Entry {
+	return c.byTitle[title]
+}
+
+// Entries returns every quiz in the catalog.
+func (c *Catalog) Entries() []*Entry {
+	return slices.Clone(c.entries)
+}
+
+// Files returns the names of the trivia files that were loaded.
+func (c *Catalog) Files() []string {
+	return slices.Clone(c.files)
+}
+
+// Titles returns the quiz titles from file. The ".json" extension may be omitted.
+func (c *Catalog) Titles(file string) ([]string, bool) {
+	entries, ok := c.byFile[file]
+	if !ok {
+		entries, ok = c.byFile[file+".json"]
+	}
+	if !ok {
+		return nil, false
+	}
+	titles := make([]string, 0, len(entries))
+	for _, e := range entries {
+		titles = append(titles, e.Title)
+	}
+	return titles, true
+}
+
+// Tagged returns the quizzes carrying tag, ignoring case.
+func (c *Catalog) Tagged(tag string) []*Entry {
+	return slices.Clone(c.byTag[strings.ToLower(tag)])
+}
+
+// Tags returns every tag in the catalog, lowercased and sorted.
+func (c *Catalog) Tags() []string {
+	tags := make([]string, 0, len(c.byTag))
+	for tag := range c.byTag {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	return tags
+}
@@ -0,0 +1,83 @@
+package trivia
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	game "server/game"
+)
+
+// Limits on quizzes submitted by a host rather than loaded from the trivia directory.
+const (
+	MaxCustomAnswers   = 100
+	MaxCustomAliases   = 10 // per answer
+	MaxCustomTitleLen  = 80
+	MaxCustomAnswerLen = 60 // applies to aliases too
+)
+
+/*
+ValidateCustomQuiz checks a host-submitted quiz and returns its answers,
+with surrounding whitespace trimmed, ready for a board. See ValidateUserQuiz.
+*/
+func ValidateCustomQuiz(title string, answers []Answer) ([]game.Answer, error) {
+	quiz := Quiz{Title: title, Answers: answers}
+	if err := ValidateUserQuiz(&quiz); err != nil {
+		return nil, err
+	}
+	return quiz.GameAnswers(), nil
+}
+
+/*
+ValidateUserQuiz checks a quiz written by a user rather than loaded from
+the trivia directory, trimming surrounding whitespace in place. It
+enforces the size limits above, the schema's metadata rules, and rejects
+anything trivia-lint would flag, such as two answers that normalize to
+the same text.
+*/
+func ValidateUserQuiz(q *Quiz) error {
+	q.Title = strings.TrimSpace(q.Title)
+	q.Description = strings.TrimSpace(q.Description)
+	q.Author = strings.TrimSpace(q.Author)
+	for i := range q.Tags {
+		q.Tags[i] = strings.TrimSpace(q.Tags[i])
+	}
+	if q.Title == "" {
+		return errors.New("title required")
+	}
+	if utf8.RuneCountInString(q.Title) > MaxCustomTitleLen {
+		return fmt.Errorf("title must be at most %d characters", MaxCustomTitleLen)
+	}
+	if len(q.Answers) == 0 {
+		return errors.New("quiz needs at least one answer")
+	}
+	if len(q.Answers) > MaxCustomAnswers {
+		return fmt.Errorf("quiz can have at most %d answers", MaxCustomAnswers)
+	}
+	for i := range q.Answers {
+		a := &q.Answers[i]
+		if len(a.Aliases) > MaxCustomAliases {
+			return fmt.Errorf("answer %q can have at most %d aliases", a.Answer, MaxCustomAliases)
+		}
+		a.Answer = strings.TrimSpace(a.Answer)
+		for j := range a.Aliases {
+			a.Aliases[j] = strings.TrimSpace(a.Aliases[j])
+		}
+		for _, name := range append([]string{a.Answer}, a.Aliases...) {
+			if name == "" {
+				return errors.New("answers and aliases must not be empty")
+			}
+			if utf8.RuneCountInString(name) > MaxCustomAnswerLen {
+				return fmt.Errorf("answer %q is longer than %d characters", name, MaxCustomAnswerLen)
+			}
+		}
+	}
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	if problems := LintQuiz(q); len(problems) > 0 {
+		return errors.New(problems[0])
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package trivia
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	game "server/game"
+)
+
+// Problem is a single issue found by Lint.
+type Problem struct {
+	File    string
+	Quiz    string // empty for problems with the file as a whole
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Quiz == "" {
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+	return fmt.Sprintf("%s: %q: %s", p.File, p.Quiz, p.Message)
+}
+
+/*
+Lint checks every trivia file in dir: that it parses against the
+schema, that no quiz title is defined twice, and that each quiz passes
+LintQuiz. Files that parse but have problems are still playable, so
+the server loads them; Lint is meant to gate content before it ships.
+*/
+func Lint(dir string) ([]Problem, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var problems []Problem
+	c := NewCatalog()
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		quizzes, err := LoadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			problems = append(problems, Problem{File: e.Name(), Message: err.Error()})
+			continue
+		}
+		for i := range quizzes {
+			for _, msg := range LintQuiz(&quizzes[i]) {
+				problems = append(problems, Problem{File: e.Name(), Quiz: quizzes[i].Title, Message: msg})
+			}
+		}
+		c.Add(e.Name(), quizzes)
+	}
+	for _, d := range c.Duplicates {
+		problems = append(problems, Problem{File: d.File, Quiz: d.Title, Message: "title is already used in " + d.Kept})
+	}
+	return problems, nil
+}
+
+// LintQuiz returns content problems in a single quiz: no answers, answers
+// or aliases that collide once normalized, and stray whitespace.
+func LintQuiz(q *Quiz) []string {
+	var problems []string
+	if len(q.Answers) == 0 {
+		problems = append(problems, "quiz has no answers")
+	}
+	if msg := checkWhitespace(q.Title); msg != "" {
+		problems = append(problems, "title "+msg)
+	}
+	for _, tag := range q.Tags {
+		if msg := checkWhitespace(tag); msg != "" {
+			problems = append(problems, fmt.Sprintf("tag %q %s", tag, msg))
+		}
+	}
+
+	owner := make(map[string]string) // normalized answer or alias -> answer it belongs to
+	for _, a := range q.Answers {
+		key := game.Normalize(a.Answer)
+		if msg := checkWhitespace(a.Answer); msg != "" {
+			problems = append(problems, fmt.Sprintf("answer %q %s", a.Answer, msg))
+		}
+		if key == "" {
+			problems = append(problems, fmt.Sprintf("answer %q has no letters or digits", a.Answer))
+			continue
+		}
+		if prev, ok := owner[key]; ok {
+			if prev == a.Answer {
+				problems = append(problems, fmt.Sprintf("duplicate answer %q", a.Answer))
+			} else {
+				problems = append(problems, fmt.Sprintf("answer %q is indistinguishable from %q", a.Answer, prev))
+			}
+			continue
+		}
+		owner[key] = a.Answer
+	}
+	for _, a := range q.Answers {
+		for _, alias := range a.Aliases {
+			if msg := checkWhitespace(alias); msg != "" {
+				problems = append(problems, fmt.Sprintf("alias %q of %q %s", alias, a.Answer, msg))
+			}
+			key := game.Normalize(alias)
+			prev, ok := owner[key]
+			switch {
+			case key == "":
+				problems = append(problems, fmt.Sprintf("alias %q of %q has no letters or digits", alias, a.Answer))
+			case !ok:
+				owner[key] = a.Answer
+			case prev == a.Answer && key == game.Normalize(a.Answer):
+				problems = append(problems, fmt.Sprintf("alias %q is the same as its answer %q", alias, a.Answer))
+			case prev == a.Answer:
+				problems = append(problems, fmt.Sprintf("alias %q of %q is listed twice", alias, a.Answer))
+			default:
+				problems = append(problems, fmt.Sprintf("alias %q of %q conflicts with %q", alias, a.Answer, prev))
+			}
+		}
+	}
+	return problems
+}
+
+// checkWhitespace describes suspicious whitespace in s, or returns "".
+func checkWhitespace(s string) string {
+	switch {
+	case s != strings.TrimSpace(s):
+		return "has leading or trailing whitespace"
+	case strings.Contains(s, "  "):
+		return "has repeated spaces"
+	case strings.IndexFunc(s, func(r rune) bool { return unicode.IsSpace(r) && r != ' ' }) >= 0:
+		return "contains a tab, newline or other non-space whitespace"
+	}
+	return ""
+}
@@ -0,0 +1,245 @@
+package trivia
+
+import (
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultPollInterval is how often Watch checks the trivia directory for changes.
+const DefaultPollInterval = 5 * time.Second
+
+// fileStamp is what polling compares to decide whether a file changed.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+/*
+A LiveCatalog holds the current Catalog for a trivia directory and can
+swap in a new one when the directory changes. Readers call Current and
+keep using the catalog they got; games created from an older catalog
+are unaffected by a reload.
+*/
+type LiveCatalog struct {
+	dir      string
+	load     func() (*Catalog, error) // builds the catalog from disk
+	current  atomic.Pointer[Catalog]
+	reloadMu sync.Mutex               // serializes Refresh, Reload, ReloadSources and SetSource
+	stamps   map[string]fileStamp     // file name -> stamp at the last load; guarded by reloadMu
+	fromDir  *Catalog                 // the directory's quizzes alone, as of the last load; guarded by reloadMu
+	sources  map[string]func() []Quiz // extra categories added after the directory; guarded by reloadMu
+}
+
+// Changes describes what a reload did to the catalog.
+type Changes struct {
+	Added    []string         // titles that are new
+	Changed  []string         // titles whose content changed
+	Removed  []string         // titles that are gone
+	Rejected map[string]error // files that failed to parse; their previous quizzes are kept
+}
+
+// Empty reports whether the reload changed nothing.
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Changed) == 0 && len(c.Removed) == 0 && len(c.Rejected) == 0
+}
+
+// OpenLiveCatalog loads the catalog in dir.
+func OpenLiveCatalog(dir string) (*LiveCatalog, error) {
+	l := &LiveCatalog{
+		dir:     dir,
+		load:    func() (*Catalog, error) { return LoadCatalog(dir) },
+		sources: make(map[string]func() []Quiz),
+	}
+	catalog, err := l.load()
+	l.current.Store(catalog)
+	l.fromDir = catalog
+	l.stamps = l.scan()
+	return l, err
+}
+
+// StaticCatalog wraps a catalog that has no directory to watch. It only
+// changes when a source is added.
+func StaticCatalog(c *Catalog) *LiveCatalog {
+	l := &LiveCatalog{
+		load:    func() (*Catalog, error) { return c.clone(), nil },
+		fromDir: c,
+		sources: make(map[string]func() []Quiz),
+	}
+	l.current.Store(c)
+	return l
+}
+
+/*
+SetSource adds quizzes that don't live in the trivia directory, such as
+published library quizzes, as the category name. quizzes is called on
+every reload, and SetSource reloads immediately; call ReloadSources when
+the source's contents change. Titles already in the directory take priority.
+*/
+func (l *LiveCatalog) SetSource(name string, quizzes func() []Quiz) (Changes, error) {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+	l.sources[name] = quizzes
+	return l.reloadLocked(l.scan())
+}
+
+// ReloadSources rebuilds the catalog from the directory as of the last load
+// and the sources' current quizzes, without rereading any trivia files.
+func (l *LiveCatalog) ReloadSources() Changes {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+	changes := l.publishLocked()
+	changes.Rejected = nil // already reported by the load that rejected them
+	return changes
+}
+
+// Current returns the catalog as of the last successful load.
+func (l *LiveCatalog) Current() *Catalog {
+	return l.current.Load()
+}
+
+// Watch polls the directory every interval until stop is closed, reloading
+// whenever a trivia file is added, removed or modified.
+func (l *LiveCatalog) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			changes, err := l.Refresh()
+			if err != nil {
+				log.Printf("trivia reload: %v", err)
+				continue
+			}
+			logChanges(changes)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Refresh reloads the catalog if any trivia file changed since the last load.
+func (l *LiveCatalog) Refresh() (Changes, error) {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+	stamps := l.scan()
+	if maps.EqualFunc(stamps, l.stamps, fileStamp.equal) {
+		return Changes{}, nil
+	}
+	return l.reloadLocked(stamps)
+}
+
+// Reload rereads the directory unconditionally.
+func (l *LiveCatalog) Reload() (Changes, error) {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+	return l.reloadLocked(l.scan())
+}
+
+func (l *LiveCatalog) reloadLocked(stamps map[string]fileStamp) (Changes, error) {
+	next, err := l.load()
+	if err != nil {
+		// keep serving the old catalog if the directory vanished
+		return Changes{}, err
+	}
+	// A half-written or broken edit shouldn't take a quiz away from players.
+	for file := range next.Rejected {
+		if entries := l.fromDir.byFile[file]; len(entries) > 0 {
+			next.Add(file, quizzesOf(entries))
+		}
+	}
+	l.fromDir = next
+	l.stamps = stamps
+	return l.publishLocked(), nil
+}
+
+// publishLocked swaps in the directory's quizzes plus every source's.
+func (l *LiveCatalog) publishLocked() Changes {
+	old := l.Current()
+	next := l.fromDir.clone()
+	for _, name := range slices.Sorted(maps.Keys(l.sources)) {
+		next.Add(name, l.sources[name]())
+	}
+	changes := diffCatalogs(old, next)
+	l.current.Store(next)
+	return changes
+}
+
+// scan stats every trivia file in the directory.
+func (l *LiveCatalog) scan() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	if l.dir == "" {
+		return stamps
+	}
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return stamps
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		stamps[e.Name()] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps
+}
+
+func (a fileStamp) equal(b fileStamp) bool {
+	return a.modTime.Equal(b.modTime) && a.size == b.size
+}
+
+func quizzesOf(entries []*Entry) []Quiz {
+	quizzes := make([]Quiz, 0, len(entries))
+	for _, e := range entries {
+		quizzes = append(quizzes, e.Quiz)
+	}
+	return quizzes
+}
+
+// diffCatalogs compares quizzes by title between two catalogs.
+func diffCatalogs(old, next *Catalog) Changes {
+	changes := Changes{Rejected: next.Rejected}
+	for title, entry := range next.byTitle {
+		prev, ok := old.byTitle[title]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, title)
+		case !reflect.DeepEqual(prev.Quiz, entry.Quiz):
+			changes.Changed = append(changes.Changed, title)
+		}
+	}
+	for title := range old.byTitle {
+		if _, ok := next.byTitle[title]; !ok {
+			changes.Removed = append(changes.Removed, title)
+		}
+	}
+	slices.Sort(changes.Added)
+	slices.Sort(changes.Changed)
+	slices.Sort(changes.Removed)
+	return changes
+}
+
+func logChanges(c Changes) {
+	if len(c.Added) > 0 {
+		log.Printf("trivia reload: added %q", c.Added)
+	}
+	if len(c.Changed) > 0 {
+		log.Printf("trivia reload: changed %q", c.Changed)
+	}
+	if len(c.Removed) > 0 {
+		log.Printf("trivia reload: removed %q", c.Removed)
+	}
+	for file, err := range c.Rejected {
+		log.Printf("trivia reload: rejected %s: %v", file, err)
+	}
+}
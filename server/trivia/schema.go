@@ -0,0 +1,182 @@
+package trivia
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	game "server/game"
+)
+
+/*
+Trivia files come in two formats.
+
+The legacy (version 1) format maps each quiz title to its answers:
+
+	{"US Capitals": ["Montgomery", {"answer": "Saint Paul", "aliases": ["St. Paul"]}]}
+
+The versioned format carries quiz metadata and per-answer details:
+
+	{
+	    "version": 2,
+	    "quizzes": [{
+	        "title": "NFL Teams",
+	        "description": "Name every NFL team.",
+	        "difficulty": "easy",
+	        "author": "sporcle",
+	        "tags": ["sports", "football"],
+	        "lobbyTime": 30,
+	        "gameTime": 180,
+	        "answers": [{"answer": "49ers", "aliases": ["Niners"], "hint": "...",
+	                     "label": "San Francisco 49ers", "group": "NFC West"}]
+	    }]
+	}
+*/
+const (
+	LegacyVersion  = 1
+	CurrentVersion = 2
+)
+
+// MinPhaseTime is the shortest lobby or game time, in seconds, a quiz may default to.
+const MinPhaseTime = 10
+
+var Difficulties = []string{"easy", "medium", "hard"}
+
+// Quiz is a single playable category along with its metadata.
+type Quiz struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description,omitempty"`
+	Difficulty  string          `json:"difficulty,omitempty"`
+	Author      string          `json:"author,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	LobbyTime   int             `json:"lobbyTime,omitempty"`  // default lobby seconds; 0 means server default
+	GameTime    int             `json:"gameTime,omitempty"`   // default game seconds; 0 means server default
+	Strictness  game.Strictness `json:"strictness,omitempty"` // default answer matching; empty means normal
+	Answers     []Answer        `json:"answers"`
+}
+
+// Answer is one square of a quiz. Answer is the canonical item placed on the
+// board; Label, when set, is a longer form for display (e.g. "San Francisco 49ers").
+type Answer struct {
+	Answer  string   `json:"answer"`
+	Aliases []string `json:"aliases,omitempty"`
+	Hint    string   `json:"hint,omitempty"`
+	Label   string   `json:"label,omitempty"`
+	Group   string   `json:"group,omitempty"` // e.g. "AFC North"
+}
+
+// UnmarshalJSON accepts either a bare answer string or a full answer object.
+// Unknown fields in an answer object are rejected, so typos don't go unnoticed.
+func (a *Answer) UnmarshalJSON(data []byte) error {
+	var item string
+	if err := json.Unmarshal(data, &item); err == nil {
+		*a = Answer{Answer: item}
+		return nil
+	}
+	type plain Answer
+	var obj plain
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&obj); err != nil {
+		return err
+	}
+	*a = Answer(obj)
+	return nil
+}
+
+// File is the versioned on-disk format.
+type File struct {
+	Version int    `json:"version"`
+	Quizzes []Quiz `json:"quizzes"`
+}
+
+// GameAnswers converts the quiz's answers into the form used to build a board.
+func (q *Quiz) GameAnswers() []game.Answer {
+	answers := make([]game.Answer, 0, len(q.Answers))
+	for _, a := range q.Answers {
+		answers = append(answers, game.Answer{Item: a.Answer, Aliases: a.Aliases})
+	}
+	return answers
+}
+
+// Titles returns the quiz titles in file order.
+func Titles(quizzes []Quiz) []string {
+	titles := make([]string, 0, len(quizzes))
+	for _, q := range quizzes {
+		titles = append(titles, q.Title)
+	}
+	return titles
+}
+
+// LoadFile reads and parses the trivia file at path.
+func LoadFile(path string) ([]Quiz, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes a trivia file in either format and checks quiz metadata.
+// Legacy quizzes are returned in title order, since JSON objects are unordered.
+func Parse(data []byte) ([]Quiz, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	var quizzes []Quiz
+	if _, versioned := probe["version"]; versioned {
+		var f File
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&f); err != nil {
+			return nil, err
+		}
+		if f.Version != CurrentVersion {
+			return nil, fmt.Errorf("unsupported trivia version %d", f.Version)
+		}
+		quizzes = f.Quizzes
+	} else {
+		var legacy map[string][]Answer
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return nil, err
+		}
+		for _, title := range slices.Sorted(maps.Keys(legacy)) {
+			quizzes = append(quizzes, Quiz{Title: title, Answers: legacy[title]})
+		}
+	}
+	for i := range quizzes {
+		if err := quizzes[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return quizzes, nil
+}
+
+// Validate checks the quiz's metadata and that every answer has text.
+func (q *Quiz) Validate() error {
+	if q.Title == "" {
+		return errors.New("quiz is missing a title")
+	}
+	if q.Difficulty != "" && !slices.Contains(Difficulties, q.Difficulty) {
+		return fmt.Errorf("quiz %q: unknown difficulty %q", q.Title, q.Difficulty)
+	}
+	if (q.LobbyTime != 0 && q.LobbyTime < MinPhaseTime) || (q.GameTime != 0 && q.GameTime < MinPhaseTime) {
+		return fmt.Errorf("quiz %q: default times must be at least %ds", q.Title, MinPhaseTime)
+	}
+	if q.Strictness != "" {
+		if _, err := game.ParseStrictness(string(q.Strictness)); err != nil {
+			return fmt.Errorf("quiz %q: %w", q.Title, err)
+		}
+	}
+	for _, a := range q.Answers {
+		if a.Answer == "" {
+			return fmt.Errorf("quiz %q: answer is missing its text", q.Title)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package clock_test
+
+import (
+	clock "server/clock"
+	"testing"
+	"time"
+)
+
+func TestFake_AdvanceDeliversEveryTick(t *testing.T) {
+	clk := clock.NewFake()
+	start := clk.Now()
+	ticker := clk.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	got := make(chan time.Time, 10)
+	go func() {
+		for tick := range ticker.C() {
+			got <- tick
+		}
+	}()
+	clk.Advance(3500 * time.Millisecond)
+	for i := 1; i <= 3; i++ {
+		tick := receive(t, got)
+		if !tick.Equal(start.Add(time.Duration(i) * time.Second)) {
+			t.Errorf("tick %d at %v, want %v", i, tick.Sub(start), time.Duration(i)*time.Second)
+		}
+	}
+	if len(got) != 0 {
+		t.Errorf("got %d extra ticks", len(got))
+	}
+	if d := clk.Now().Sub(start); d != 3500*time.Millisecond {
+		t.Errorf("Now moved by %v, want 3.5s", d)
+	}
+}
+
+func TestFake_ResetAndStop(t *testing.T) {
+	clk := clock.NewFake()
+	ticker := clk.NewTicker(time.Second)
+	got := make(chan time.Time, 10)
+	go func() {
+		for tick := range ticker.C() {
+			got <- tick
+		}
+	}()
+
+	clk.Advance(500 * time.Millisecond)
+	ticker.Reset(time.Second) // next tick is now 1.5s after start
+	clk.Advance(900 * time.Millisecond)
+	if len(got) != 0 {
+		t.Fatalf("ticked %d times before the reset interval passed", len(got))
+	}
+	clk.Advance(100 * time.Millisecond)
+	receive(t, got)
+
+	ticker.Stop()
+	clk.Advance(5 * time.Second) // must not block on a stopped ticker
+	if len(got) != 0 {
+		t.Errorf("stopped ticker kept ticking")
+	}
+}
+
+// receive waits briefly for a tick the test's reader goroutine has forwarded.
+func receive(t *testing.T, got <-chan time.Time) time.Time {
+	t.Helper()
+	select {
+	case tick := <-got:
+		return tick
+	case <-time.After(time.Second):
+		t.Fatal("expected a tick")
+		return time.Time{}
+	}
+}
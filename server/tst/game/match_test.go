@@ -0,0 +1,125 @@
+package game_test
+
+import (
+	"testing"
+
+	game "server/game"
+	test "server/tst"
+)
+
+var capitals = []game.Answer{
+	{Item: "Saint Paul", Aliases: []string{"St. Paul"}},
+	{Item: "Phoenix"},
+	{Item: "Salt Lake City"},
+	{Item: "Jefferson City"},
+}
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"  St. Paul ":      "st paul",
+		"SALT   lake city": "salt lake city",
+		"Zürich":           "zurich",
+		"Bosnia & Herz.":   "bosnia and herz",
+		"Côte d'Ivoire":    "cote divoire",
+		"São Tomé":         "sao tome",
+		"...":              "",
+	}
+	for in, want := range cases {
+		if got := game.Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMatcher_Normal(t *testing.T) {
+	m := game.NewMatcher(capitals, game.StrictnessNormal)
+	cases := map[string]string{
+		"Phoenix":        "Phoenix",
+		"phoenix ":       "Phoenix",
+		"saint paul":     "Saint Paul",
+		"St. Paul":       "Saint Paul",
+		"st paul":        "Saint Paul",
+		"salt-lake city": "Salt Lake City",
+	}
+	for guess, want := range cases {
+		got, ok := m.Match(guess)
+		if !ok || got != want {
+			t.Errorf("Match(%q) = %q, %v; want %q, true", guess, got, ok, want)
+		}
+	}
+	if got, ok := m.Match("Pheonix"); ok {
+		t.Errorf("Match(Pheonix) with normal strictness = %q, want no match", got)
+	}
+}
+
+func TestMatcher_Exact(t *testing.T) {
+	m := game.NewMatcher(capitals, game.StrictnessExact)
+	if got, ok := m.Match("St. Paul"); !ok || got != "Saint Paul" {
+		t.Errorf("Match(St. Paul) = %q, %v; want alias to match exactly", got, ok)
+	}
+	for _, guess := range []string{"phoenix", "Phoenix ", "st paul"} {
+		if got, ok := m.Match(guess); ok {
+			t.Errorf("Match(%q) with exact strictness = %q, want no match", guess, got)
+		}
+	}
+}
+
+func TestMatcher_LenientToleratesTypos(t *testing.T) {
+	m := game.NewMatcher(capitals, game.StrictnessLenient)
+	cases := map[string]string{
+		"Pheonix":       "Phoenix",
+		"salt lak city": "Salt Lake City",
+		"jeferson cty":  "Jefferson City",
+	}
+	for guess, want := range cases {
+		got, ok := m.Match(guess)
+		if !ok || got != want {
+			t.Errorf("Match(%q) = %q, %v; want %q, true", guess, got, ok, want)
+		}
+	}
+	if got, ok := m.Match("Denver"); ok {
+		t.Errorf("Match(Denver) = %q, want no match", got)
+	}
+}
+
+func TestMatcher_ShortAnswersNeedExactSpelling(t *testing.T) {
+	m := game.NewMatcher([]game.Answer{{Item: "Nets"}, {Item: "Jets"}}, game.StrictnessLenient)
+	if got, ok := m.Match("Bets"); ok {
+		t.Errorf("Match(Bets) = %q, want no match for short answers", got)
+	}
+}
+
+func TestMatcher_AmbiguousAliasNeverMatches(t *testing.T) {
+	answers := []game.Answer{
+		{Item: "Georgia (country)", Aliases: []string{"Georgia"}},
+		{Item: "Georgia (state)", Aliases: []string{"georgia"}},
+	}
+	m := game.NewMatcher(answers, game.StrictnessNormal)
+	if got, ok := m.Match("GEORGIA"); ok {
+		t.Errorf("Match(GEORGIA) = %q, want no match for an ambiguous alias", got)
+	}
+}
+
+func TestParseStrictness(t *testing.T) {
+	if s, err := game.ParseStrictness(""); err != nil || s != game.StrictnessNormal {
+		t.Errorf("ParseStrictness(\"\") = %q, %v; want normal", s, err)
+	}
+	if _, err := game.ParseStrictness("loose"); err == nil {
+		t.Error("ParseStrictness(loose) expected error")
+	}
+}
+
+func TestSetAnswers_BoardUsesCanonicalItems(t *testing.T) {
+	m := game.NewManager("US Capitals", "ABC123", test.LOBBY_TIME, test.GAME_TIME, nil, game.DefaultOptions())
+	m.SetAnswers(capitals, game.StrictnessNormal)
+	snap, _ := m.Snapshot()
+	if len(snap.Board) != len(capitals) {
+		t.Fatalf("Board size = %d, want %d", len(snap.Board), len(capitals))
+	}
+	if _, ok := snap.Board["St. Paul"]; ok {
+		t.Error("aliases should not be added to the Board")
+	}
+	if item := test.Guess(t, m, "st paul"); item != "Saint Paul" {
+		t.Errorf("guessing st paul claimed %q, want Saint Paul", item)
+	}
+}
@@ -0,0 +1,137 @@
+package game_test
+
+import (
+	"errors"
+	"fmt"
+	clock "server/clock"
+	game "server/game"
+	test "server/tst"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPhase_CanTransition(t *testing.T) {
+	allowed := [][2]game.Phase{
+		{game.PhaseLobby, game.PhaseCountdown},
+		{game.PhaseCountdown, game.PhaseRunning},
+		{game.PhaseRunning, game.PhasePaused},
+		{game.PhasePaused, game.PhaseRunning},
+		{game.PhasePaused, game.PhaseFinished},
+		{game.PhaseFinished, game.PhaseClosed},
+	}
+	for _, tr := range allowed {
+		if !tr[0].CanTransition(tr[1]) {
+			t.Errorf("%s -> %s not allowed, want allowed", tr[0], tr[1])
+		}
+	}
+	forbidden := [][2]game.Phase{
+		{game.PhaseLobby, game.PhaseFinished},
+		{game.PhaseCountdown, game.PhaseLobby}, // an emptied lobby closes instead
+		{game.PhaseRunning, game.PhaseLobby},
+		{game.PhaseFinished, game.PhaseRunning},
+		{game.PhaseClosed, game.PhaseLobby},
+		{game.PhaseRunning, game.PhaseRunning},
+	}
+	for _, tr := range forbidden {
+		if tr[0].CanTransition(tr[1]) {
+			t.Errorf("%s -> %s allowed, want forbidden", tr[0], tr[1])
+		}
+	}
+}
+
+func TestPhase_Accepts(t *testing.T) {
+	claim := game.PlayerRequest{Item: "Boise"}
+	over := game.PlayerRequest{Item: game.GameOverItem}
+	cases := []struct {
+		phase       game.Phase
+		claim, over bool
+	}{
+		{game.PhaseLobby, false, false},
+		{game.PhaseCountdown, false, false},
+		{game.PhaseRunning, true, false},
+		{game.PhasePaused, false, false},
+		{game.PhaseFinished, false, true},
+	}
+	for _, c := range cases {
+		if got := c.phase.Accepts(claim); got != c.claim {
+			t.Errorf("%s accepts a claim = %v, want %v", c.phase, got, c.claim)
+		}
+		if got := c.phase.Accepts(over); got != c.over {
+			t.Errorf("%s accepts GAME_OVER = %v, want %v", c.phase, got, c.over)
+		}
+	}
+}
+
+func TestManager_Commands(t *testing.T) {
+	m := game.NewManager("US Capitals", "ABC123", test.LOBBY_TIME, test.GAME_TIME, nil, game.DefaultOptions())
+	steps := []struct {
+		cmd   game.Command
+		ok    bool
+		phase game.Phase
+	}{
+		{game.CommandPause, false, game.PhaseLobby},
+		{game.CommandStart, true, game.PhaseRunning},
+		{game.CommandStart, false, game.PhaseRunning},
+		{game.CommandResume, false, game.PhaseRunning},
+		{game.CommandPause, true, game.PhasePaused},
+		{game.CommandResume, true, game.PhaseRunning},
+		{game.CommandEnd, true, game.PhaseFinished},
+		{game.CommandPause, false, game.PhaseFinished},
+	}
+	for _, s := range steps {
+		err := m.Command(s.cmd)
+		if s.ok && err != nil {
+			t.Errorf("%s: %v", s.cmd, err)
+		}
+		if !s.ok && !errors.Is(err, game.ErrInvalidTransition) {
+			t.Errorf("%s: err = %v, want ErrInvalidTransition", s.cmd, err)
+		}
+		if snap, _ := m.Snapshot(); snap.Phase != s.phase {
+			t.Errorf("after %s: phase = %s, want %s", s.cmd, snap.Phase, s.phase)
+		}
+	}
+	if err := m.Command("restart"); !errors.Is(err, game.ErrInvalidCommand) {
+		t.Errorf("unknown command: err = %v, want ErrInvalidCommand", err)
+	}
+}
+
+// A message queued just as the loop exits is never handled, so queries on a
+// closed game must give up instead of waiting for a reply.
+func TestManager_ClosedGameAnswersAtOnce(t *testing.T) {
+	clk := clock.NewFake()
+	m := game.NewManager("US Capitals", "ABC123", test.LOBBY_TIME, test.GAME_TIME, clk, game.DefaultOptions())
+	go m.Run()
+	clk.WaitForTickers(1)
+	m.Command(game.CommandStart)
+	m.Command(game.CommandEnd)
+	clk.Advance(game.DefaultLingerTime * time.Second)
+	<-m.Done()
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, _, err := m.Join(fmt.Sprintf("player%d", i), ""); err != game.ErrGameClosed {
+				t.Errorf("join: err = %v, want ErrGameClosed", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, ok := m.Snapshot(); ok {
+				t.Error("snapshot of a closed game: ok = true")
+			}
+		}()
+	}
+	returned := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Join or Snapshot blocked on a closed game")
+	}
+}
@@ -0,0 +1,70 @@
+package gameflow
+
+import (
+	"reflect"
+	game "server/game"
+	test "server/tst"
+	"testing"
+	"time"
+)
+
+// coopGame is a three-square cooperative game, past the lobby.
+var coopGame = gameSetup{
+	squares: []string{"Boise", "Salem", "Helena"},
+	options: func(o *game.Options) { o.Mode = game.ModeCoop },
+	running: true,
+}
+
+// leaderboard returns the one Leaderboard event p got.
+func leaderboard(t *testing.T, p *game.Player) game.GameEvent {
+	t.Helper()
+	boards := ofType(drain(p), "Leaderboard")
+	if len(boards) != 1 {
+		t.Fatalf("%s got %d leaderboards, want 1", p.Username, len(boards))
+	}
+	return boards[0]
+}
+
+func TestCoop_FindingEverySquareSucceeds(t *testing.T) {
+	m, _, players := newGame(t, coopGame, "LeBron", "Steph")
+	for _, c := range [][2]string{{"LeBron", "Boise"}, {"Steph", "Salem"}, {"LeBron", "Helena"}} {
+		m.Claim(game.PlayerRequest{Username: c[0], Item: c[1]})
+	}
+	if snap, _ := m.Snapshot(); snap.Phase != game.PhaseFinished {
+		t.Fatalf("phase = %s, want finished once the board is full", snap.Phase)
+	}
+	board := leaderboard(t, players[1])
+	want := &game.CoopResult{Success: true, Found: 3, Total: 3, Completion: 100, Missed: []string{}}
+	if !reflect.DeepEqual(board.Coop, want) {
+		t.Errorf("coop = %+v, want %+v", board.Coop, want)
+	}
+	if got := board.Leaderboard[0]; got.Username != "LeBron" || !reflect.DeepEqual(got.Items, []string{"Boise", "Helena"}) {
+		t.Errorf("LeBron found %v, want Boise and Helena", got.Items)
+	}
+}
+
+func TestCoop_TimeoutFails(t *testing.T) {
+	m, clk, players := newGame(t, coopGame, "LeBron")
+	m.Claim(game.PlayerRequest{Username: "LeBron", Item: "Salem"})
+	m.Snapshot()
+	clk.Advance(test.GAME_TIME * time.Second)
+
+	want := &game.CoopResult{Success: false, Found: 1, Total: 3, Completion: 33.3, Missed: []string{"Boise", "Helena"}}
+	if board := leaderboard(t, players[0]); !reflect.DeepEqual(board.Coop, want) {
+		t.Errorf("coop = %+v, want %+v", board.Coop, want)
+	}
+	if results, _ := m.Results(); !reflect.DeepEqual(results.Coop, want) || results.Settings.Mode != game.ModeCoop {
+		t.Errorf("results = %+v in mode %s, want %+v in coop", results.Coop, results.Settings.Mode, want)
+	}
+}
+
+func TestCoop_CompetitiveGamesHaveNoCoopResult(t *testing.T) {
+	m, clk, players := newTimedGame(t, "LeBron")
+	clk.Advance(test.LOBBY_TIME * time.Second)
+	if err := m.Command(game.CommandEnd); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	if board := leaderboard(t, players[0]); board.Coop != nil {
+		t.Errorf("coop = %+v, want none", board.Coop)
+	}
+}
@@ -0,0 +1,136 @@
+package gameflow
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	game "server/game"
+	protocol "server/protocol"
+	test "server/tst"
+	"testing"
+	"time"
+)
+
+func metric(name string) int64 {
+	return expvar.Get(name).(*expvar.Int).Value()
+}
+
+func TestDelivery_CoalescesUpdatesButKeepsCriticalEvents(t *testing.T) {
+	_, clk, players := newTimedGame(t, "LeBron")
+	coalesced := metric("game.events_coalesced")
+
+	// nobody reads LeBron's events for the whole game
+	clk.Advance(time.Duration(test.LOBBY_TIME+test.GAME_TIME) * time.Second)
+	events := drain(players[0])
+
+	counts := map[string]int{}
+	var phases []game.Phase
+	for _, e := range events {
+		counts[e.Type]++
+		if e.Type == "Phase" {
+			phases = append(phases, e.Phase)
+		}
+	}
+	for _, eventType := range []string{"Time", "Players"} {
+		if counts[eventType] > 1 {
+			t.Errorf("%d %s events queued, want them coalesced into 1", counts[eventType], eventType)
+		}
+	}
+	if counts["Start"] != 1 || counts["Leaderboard"] != 1 {
+		t.Errorf("Start = %d, Leaderboard = %d; want 1 each", counts["Start"], counts["Leaderboard"])
+	}
+	want := []game.Phase{game.PhaseCountdown, game.PhaseRunning, game.PhaseFinished}
+	if len(phases) != len(want) {
+		t.Fatalf("phase events = %v, want %v", phases, want)
+	}
+	for i := range want {
+		if phases[i] != want[i] {
+			t.Errorf("phase events = %v, want %v", phases, want)
+			break
+		}
+	}
+	if metric("game.events_coalesced") <= coalesced {
+		t.Error("game.events_coalesced did not increase")
+	}
+
+	// the final state comes after the events that led to it
+	if last := events[len(events)-1]; last.Type != "Time" {
+		t.Errorf("last event = %s, want the latest Time", last.Type)
+	}
+}
+
+func TestDelivery_SlowPlayerIsCutOff(t *testing.T) {
+	_, _, players := newTimedGame(t, "LeBron")
+	p := players[0]
+	disconnects := metric("game.slow_disconnects")
+	dropped := metric("game.events_dropped")
+
+	for range game.MaxPending + 1 {
+		p.SendError(protocol.ErrNotAccepted, "too slow")
+	}
+	if got := metric("game.slow_disconnects"); got != disconnects+1 {
+		t.Errorf("game.slow_disconnects = %d, want %d", got, disconnects+1)
+	}
+	if got := metric("game.events_dropped"); got <= dropped {
+		t.Error("game.events_dropped did not increase")
+	}
+	if pending := drain(p); len(pending) != 0 {
+		t.Errorf("%d events still queued after the player was cut off", len(pending))
+	}
+}
+
+func TestDelivery_ReconnectAfterFinishGetsLeaderboard(t *testing.T) {
+	m, clk, url := setupServer(t)
+	lebron := dialPlayer(t, url, m.Code, "LeBron")
+	defer lebron.Close()
+	steph, token := dialSession(t, url, m.Code, "Steph", "")
+	runFor(m, clk, test.LOBBY_TIME)
+	readUntil(t, steph, "Start")
+	if err := steph.WriteJSON(map[string]string{"Item": "Sacramento"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	readUntil(t, steph, "Claimed")
+
+	// Steph is cut off, and misses the end of the game
+	steph.Close()
+	waitForSnapshot(t, m, func(s game.Snapshot) bool {
+		for _, p := range s.Players {
+			if p.Username == "Steph" {
+				return !p.Connected
+			}
+		}
+		return false
+	})
+	if err := m.Command(game.CommandEnd); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	readUntil(t, lebron, "Leaderboard")
+
+	steph, _ = dialSession(t, url, m.Code, "Steph", token)
+	defer steph.Close()
+	snap := readUntil(t, steph, "Snapshot")
+	if snap.Phase != game.PhaseFinished {
+		t.Fatalf("snapshot phase = %s, want finished", snap.Phase)
+	}
+	if len(snap.Leaderboard) == 0 || snap.Leaderboard[0].Username != "Steph" || snap.Leaderboard[0].Count != 1 {
+		t.Errorf("snapshot leaderboard = %+v, want Steph first with 1", snap.Leaderboard)
+	}
+}
+
+func TestDelivery_MetricsHandlerOnlyServesGameVars(t *testing.T) {
+	rec := httptest.NewRecorder()
+	game.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/vars", nil))
+
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	if _, ok := vars["game.events_sent"]; !ok {
+		t.Error("game.events_sent missing")
+	}
+	for _, private := range []string{"cmdline", "memstats"} {
+		if _, ok := vars[private]; ok {
+			t.Errorf("%s is exposed", private)
+		}
+	}
+}
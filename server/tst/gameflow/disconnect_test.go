@@ -0,0 +1,163 @@
+package gameflow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	clock "server/clock"
+	game "server/game"
+	gameinit "server/game-init"
+	"server/state"
+	test "server/tst"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// setupServer creates a game on a fake clock behind a test server.
+func setupServer(t *testing.T) (*game.Manager, *clock.Fake, string) {
+	t.Helper()
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = testTriviaPath
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	clk := clock.NewFake()
+	globalState.SetClock(clk)
+	m := globalState.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
+	if m == nil {
+		t.Fatal("Create failed")
+	}
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, globalState)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return m, clk, server.URL
+}
+
+type playersEvent struct {
+	Type    string
+	Players map[string]struct {
+		Color     string `json:"color"`
+		Connected bool   `json:"connected"`
+	}
+}
+
+// readPlayersUntil reads Players events until done is satisfied.
+func readPlayersUntil(t *testing.T, conn *websocket.Conn, done func(playersEvent) bool) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		var event playersEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("waiting for a Players update: %v", err)
+		}
+		if event.Type == "Players" && done(event) {
+			return
+		}
+	}
+}
+
+// waitForSnapshot polls the game until cond holds.
+func waitForSnapshot(t *testing.T, m *game.Manager, cond func(game.Snapshot) bool) game.Snapshot {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		snap, ok := m.Snapshot()
+		if !ok || cond(snap) {
+			return snap
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("game never reached the expected state")
+	return game.Snapshot{}
+}
+
+func TestDisconnect_LobbyFreesNameAndColor(t *testing.T) {
+	m, _, url := setupServer(t)
+	lebron := dialPlayer(t, url, m.Code, "LeBron")
+	defer lebron.Close()
+	steph := dialPlayer(t, url, m.Code, "Steph")
+	stephColor := m.Player("Steph").Color
+	steph.Close()
+
+	readPlayersUntil(t, lebron, func(e playersEvent) bool {
+		_, still := e.Players["Steph"]
+		return !still
+	})
+	if m.HasPlayer("Steph") {
+		t.Fatal("Steph is still in the lobby after leaving")
+	}
+
+	// the name and color are free again
+	kd := dialPlayer(t, url, m.Code, "Steph")
+	defer kd.Close()
+	if color := m.Player("Steph").Color; color != stephColor {
+		t.Errorf("rejoining player got color %s, want the freed %s", color, stephColor)
+	}
+}
+
+func TestDisconnect_MidGameKeepsScore(t *testing.T) {
+	m, clk, url := setupServer(t)
+	lebron := dialPlayer(t, url, m.Code, "LeBron")
+	defer lebron.Close()
+	steph := dialPlayer(t, url, m.Code, "Steph")
+	runFor(m, clk, test.LOBBY_TIME)
+	readUntil(t, steph, "Start")
+
+	if err := steph.WriteJSON(map[string]string{"Item": "Sacramento"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	waitForSnapshot(t, m, func(s game.Snapshot) bool { return s.Board["Sacramento"] == "Steph" })
+	steph.Close()
+
+	readPlayersUntil(t, lebron, func(e playersEvent) bool {
+		p, ok := e.Players["Steph"]
+		return ok && !p.Connected
+	})
+	snap, _ := m.Snapshot()
+	for _, p := range snap.Players {
+		if p.Username == "Steph" && (p.Connected || p.Correct != 1) {
+			t.Errorf("Steph = %+v, want disconnected with 1 correct", p)
+		}
+	}
+	if snap.Board["Sacramento"] != "Steph" {
+		t.Error("Steph lost her square after disconnecting")
+	}
+}
+
+func TestDisconnect_EmptyLobbyIsTornDown(t *testing.T) {
+	m, _, url := setupServer(t)
+	conn := dialPlayer(t, url, m.Code, "LeBron")
+	conn.Close()
+	select {
+	case <-m.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lobby everyone left was not torn down")
+	}
+}
+
+func TestDisconnect_AbandonedGameIsTornDown(t *testing.T) {
+	m, clk, url := setupServer(t)
+	conn := dialPlayer(t, url, m.Code, "LeBron")
+	runFor(m, clk, test.LOBBY_TIME)
+	readUntil(t, conn, "Start")
+	// paused, so the game clock can't end the game first
+	if err := m.Command(game.CommandPause); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	conn.Close()
+	waitForSnapshot(t, m, func(s game.Snapshot) bool { return !s.Players[0].Connected })
+
+	clk.Advance(time.Duration(game.AbandonTime-1) * time.Second)
+	if _, ok := m.Snapshot(); !ok {
+		t.Fatal("game closed before AbandonTime")
+	}
+	clk.Advance(time.Second)
+	select {
+	case <-m.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("game everyone left was not torn down")
+	}
+}
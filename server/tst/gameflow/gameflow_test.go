@@ -3,12 +3,15 @@ package gameflow
 import (
 	"net/http"
 	"net/http/httptest"
+	clock "server/clock"
 	game "server/game"
 	gameinit "server/game-init"
+	protocol "server/protocol"
 	"server/state"
 	test "server/tst"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -16,15 +19,18 @@ import (
 const testTriviaPath = "../../../trivia"
 
 // setupGameWithConn creates a game, HTTP server, and a connected WebSocket client.
-// Returns the manager, game code, conn, and the player. Caller must defer conn.Close().
+// Returns the manager, game code, conn, the player, and the fake clock the game
+// keeps time with. Caller must defer conn.Close().
 // The Connect handler sends {"type":"success"} first; consume it before testing Read/Write.
-func setupGameWithConn(t *testing.T) (*game.Manager, string, *websocket.Conn, *game.Player) {
+func setupGameWithConn(t *testing.T) (*game.Manager, string, *websocket.Conn, *game.Player, *clock.Fake) {
 	t.Helper()
 	saved := state.TriviaBasePath
 	state.TriviaBasePath = testTriviaPath
 	defer func() { state.TriviaBasePath = saved }()
 
 	globalState := state.NewGlobalState()
+	clk := clock.NewFake()
+	globalState.SetClock(clk)
 	m := globalState.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
 	if m == nil {
 		t.Fatal("Create failed")
@@ -53,22 +59,22 @@ func setupGameWithConn(t *testing.T) (*game.Manager, string, *websocket.Conn, *g
 		t.Fatalf("expected type=success, got %v", successMsg)
 	}
 
-	player := m.Players["LeBron"]
+	player := m.Player("LeBron")
 	if player == nil {
 		conn.Close()
 		t.Fatal("player LeBron not in game")
 	}
-	return m, code, conn, player
+	return m, code, conn, player, clk
 }
 
 func TestWrite_SendsEventsToWebSocket(t *testing.T) {
-	m, code, conn, _ := setupGameWithConn(t)
+	m, code, conn, _, clk := setupGameWithConn(t)
 	defer conn.Close()
 
-	// Start Run() so Read/Write routines run (Run calls StartRoutines)
-	go m.Run()
+	// Start Run() and let the lobby run out
+	runFor(m, clk, test.LOBBY_TIME)
 
-	// Drain messages until game starts (timer fires quickly)
+	// Drain messages until game starts
 	for {
 		var msg map[string]interface{}
 		if err := conn.ReadJSON(&msg); err != nil {
@@ -82,7 +88,7 @@ func TestWrite_SendsEventsToWebSocket(t *testing.T) {
 		}
 	}
 	// Write a message into the connection (as client would); Read() picks it up,
-	// Run() processes it and BroadcastState, Write() sends the response back.
+	// Run() processes it and broadcasts the claim, Write() sends the response back.
 	req := map[string]string{
 		"username": "LeBron",
 		"code":     code,
@@ -92,28 +98,28 @@ func TestWrite_SendsEventsToWebSocket(t *testing.T) {
 		t.Fatalf("WriteJSON: %v", err)
 	}
 
-	// Verify we get the board update response back through the WebSocket
+	// Verify we get the Claimed event back through the WebSocket
 	iters := 100
 	for range iters {
 		var got map[string]interface{}
 		if err := conn.ReadJSON(&got); err != nil {
 			t.Fatalf("ReadJSON response: %v", err)
 		}
-		if got["Type"] == "Board" {
+		if got["Type"] == "Claimed" {
 			return
 		}
 	}
-	t.Errorf("Did not recieve a message of type board in %d iters", iters)
+	t.Errorf("Did not recieve a Claimed event in %d iters", iters)
 
 }
 
 func TestRead_ValidRequestAppearsOnInboundRequests(t *testing.T) {
-	m, code, conn, _ := setupGameWithConn(t)
+	m, code, conn, _, clk := setupGameWithConn(t)
 	defer conn.Close()
 
-	go m.Run()
+	runFor(m, clk, test.LOBBY_TIME)
 
-	// Drain messages until game starts (timer fires quickly)
+	// Drain messages until game starts
 	for {
 		var msg map[string]interface{}
 		if err := conn.ReadJSON(&msg); err != nil {
@@ -142,36 +148,27 @@ func TestRead_ValidRequestAppearsOnInboundRequests(t *testing.T) {
 		if err := conn.ReadJSON(&got); err != nil {
 			t.Fatalf("ReadJSON response: %v", err)
 		}
-		if got["Type"] == "Board" {
-			stateVal, ok := got["State"]
-			if !ok {
-				continue
-			}
-			stateMap, ok := stateVal.(map[string]interface{})
-			if !ok {
+		if got["Type"] == "Claimed" {
+			if got["Item"] != "Olympia" {
 				continue
 			}
-			player, ok := stateMap["Olympia"]
-			if !ok || player == nil {
-				continue
-			}
-			playerMap, ok := player.(map[string]interface{})
+			playerMap, _ := got["Player"].(map[string]interface{})
 			if playerMap["username"] != "LeBron" {
 				continue
 			}
 			return
 		}
 	}
-	t.Errorf("Did not recieve a message of type board with LeBron: Olympia mapping in %d iters", iters)
+	t.Errorf("Did not recieve a Claimed event for LeBron: Olympia in %d iters", iters)
 }
 
 func TestRead_InvalidRequestIgnored(t *testing.T) {
-	m, code, conn, _ := setupGameWithConn(t)
+	m, code, conn, _, clk := setupGameWithConn(t)
 	defer conn.Close()
 
-	go m.Run()
+	runFor(m, clk, test.LOBBY_TIME)
 
-	// Drain messages until game starts (timer fires quickly)
+	// Drain messages until game starts
 	for {
 		var msg map[string]interface{}
 		if err := conn.ReadJSON(&msg); err != nil {
@@ -198,17 +195,17 @@ func TestRead_InvalidRequestIgnored(t *testing.T) {
 		if err := conn.ReadJSON(&got); err != nil {
 			t.Fatalf("ReadJSON response: %v", err)
 		}
-		if got["Type"] == "Board" {
+		if got["Type"] == "Claimed" {
 			found = true
 			break
 		}
 	}
 	if !found {
-		t.Fatalf("Did not recieve a message of type board in %d iters", iters)
+		t.Fatalf("Did not recieve a Claimed event in %d iters", iters)
 	}
 
-	// Send invalid (empty username) - should be ignored
-	invalidReq := map[string]string{"username": "", "code": code, "Item": "Denver"}
+	// Send invalid (another player's username) - should be ignored
+	invalidReq := map[string]string{"username": "Steph", "code": code, "Item": "Denver"}
 	if err := conn.WriteJSON(invalidReq); err != nil {
 		t.Fatalf("WriteJSON invalid: %v", err)
 	}
@@ -225,28 +222,19 @@ func TestRead_InvalidRequestIgnored(t *testing.T) {
 		if err := conn.ReadJSON(&got); err != nil {
 			t.Fatalf("ReadJSON response: %v", err)
 		}
-		if got["Type"] == "Board" {
+		if got["Type"] == "Claimed" {
 			// Invalid request should have been skipped; we got Oklahoma City not something from invalid
-			stateVal, ok := got["State"]
-			if !ok {
-				continue
-			}
-			stateMap, ok := stateVal.(map[string]interface{})
-			if !ok {
-				continue
+			if got["Item"] != "Oklahoma City" {
+				t.Fatalf("claimed %v, want Oklahoma City", got["Item"])
 			}
-			city, ok := stateMap["Oklahoma City"]
-			if !ok || city == nil {
-				continue
-			}
-			playerMap, ok := city.(map[string]interface{})
+			playerMap, _ := got["Player"].(map[string]interface{})
 			if playerMap["username"] != "LeBron" {
-				continue
+				t.Fatalf("Oklahoma City claimed by %v, want LeBron", playerMap["username"])
 			}
 			return
 		}
 	}
-	t.Fatalf("Did not recieve a message of type board with LeBron: Oklahoma City mapping in %d iters", iters)
+	t.Fatalf("Did not recieve a Claimed event for LeBron: Oklahoma City in %d iters", iters)
 
 }
 
@@ -256,6 +244,8 @@ func TestRun_ProcessesInboundRequestAndBroadcastsState(t *testing.T) {
 	defer func() { state.TriviaBasePath = saved }()
 
 	globalState := state.NewGlobalState()
+	clk := clock.NewFake()
+	globalState.SetClock(clk)
 	m := globalState.Create("US Capitals", 2, 2)
 	if m == nil {
 		t.Fatal("Create failed")
@@ -280,10 +270,10 @@ func TestRun_ProcessesInboundRequestAndBroadcastsState(t *testing.T) {
 		t.Fatalf("read success: %v", err)
 	}
 
-	// Start Run (player already connected, so StartRoutines will pick them up)
-	go m.Run()
+	// Start Run (player already connected, so their routines are running)
+	runFor(m, clk, 2)
 
-	// Drain messages until we get "Start" (game started). Timer fires every 60ns so this is fast.
+	// Drain messages until we get "Start" (game started).
 	for {
 		var msg map[string]interface{}
 		if err := conn.ReadJSON(&msg); err != nil {
@@ -310,32 +300,192 @@ func TestRun_ProcessesInboundRequestAndBroadcastsState(t *testing.T) {
 		t.Fatalf("WriteJSON: %v", err)
 	}
 
-	// Expect a board update
+	// Expect a Claimed event
 	iters := 10
-	var boardMsg map[string]interface{}
+	var claimMsg map[string]interface{}
 	for range iters {
-		if err := conn.ReadJSON(&boardMsg); err != nil {
-			t.Fatalf("ReadJSON board: %v", err)
+		if err := conn.ReadJSON(&claimMsg); err != nil {
+			t.Fatalf("ReadJSON claim: %v", err)
 		}
-		if boardMsg["Type"] == "Board" {
-			stateVal, ok := boardMsg["State"]
-			if !ok {
+		if claimMsg["Type"] == "Claimed" {
+			if claimMsg["Item"] != "Sacramento" {
 				continue
 			}
-			stateMap, ok := stateVal.(map[string]interface{})
-			if !ok {
-				continue
-			}
-			city, ok := stateMap["Sacramento"]
-			if !ok || city == nil {
-				continue
-			}
-			playerMap, ok := city.(map[string]interface{})
+			playerMap, _ := claimMsg["Player"].(map[string]interface{})
 			if playerMap["username"] != "Steph" {
 				continue
 			}
 			return
 		}
 	}
-	t.Fatalf("Did not recieve a message of type board with Steph: Sacramento mapping in %d iters", iters)
+	t.Fatalf("Did not recieve a Claimed event for Steph: Sacramento in %d iters", iters)
+}
+
+// runFor starts the game's clock and advances it by seconds.
+func runFor(m *game.Manager, clk *clock.Fake, seconds int) {
+	go m.Run()
+	clk.WaitForTickers(1)
+	clk.Advance(time.Duration(seconds) * time.Second)
+}
+
+// dialPlayer joins the game as user and consumes the "success" message.
+func dialPlayer(t *testing.T, serverURL, code, user string) *websocket.Conn {
+	t.Helper()
+	wsURL := "ws" + strings.TrimPrefix(serverURL, "http") + "/ws?game=" + code + "&user=" + user
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("WebSocket dial %s: %v", user, err)
+	}
+	var successMsg map[string]string
+	if err := conn.ReadJSON(&successMsg); err != nil || successMsg["type"] != "success" {
+		conn.Close()
+		t.Fatalf("join %s: got %v, %v", user, successMsg, err)
+	}
+	return conn
+}
+
+// readUntil reads events until one has the given type.
+func readUntil(t *testing.T, conn *websocket.Conn, eventType string) game.GameEvent {
+	t.Helper()
+	for {
+		var event game.GameEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("waiting for %s: %v", eventType, err)
+		}
+		if event.Type == eventType {
+			return event
+		}
+	}
+}
+
+func TestRead_SpoofedClaimNotCredited(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = testTriviaPath
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	clk := clock.NewFake()
+	globalState.SetClock(clk)
+	m := globalState.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
+	if m == nil {
+		t.Fatal("Create failed")
+	}
+	code := m.Code
+
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, globalState)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	lebron := dialPlayer(t, server.URL, code, "LeBron")
+	defer lebron.Close()
+	steph := dialPlayer(t, server.URL, code, "Steph")
+	defer steph.Close()
+
+	runFor(m, clk, test.LOBBY_TIME)
+	readUntil(t, lebron, "Start")
+
+	// LeBron's socket tries to claim squares as Steph, and in another game
+	spoofs := []map[string]string{
+		{"username": "Steph", "code": code, "Item": "Sacramento"},
+		{"username": "LeBron", "code": "ZZZZZZ", "Item": "Austin"},
+	}
+	for _, req := range spoofs {
+		if err := lebron.WriteJSON(req); err != nil {
+			t.Fatalf("WriteJSON: %v", err)
+		}
+		if event := readUntil(t, lebron, "Error"); event.Error == "" {
+			t.Errorf("spoofed %v: Error frame has no message", req)
+		}
+	}
+
+	// identity fields may be omitted entirely; the claim goes to the connection's player
+	if err := lebron.WriteJSON(map[string]string{"Item": "Olympia"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	claimed := readUntil(t, lebron, "Claimed")
+	if claimed.Item != "Olympia" || claimed.Player.Username != "LeBron" || claimed.Seq != 1 {
+		t.Errorf("first claim = %s by %s (seq %d), want Olympia by LeBron (seq 1)", claimed.Item, claimed.Player.Username, claimed.Seq)
+	}
+	snap, _ := m.Snapshot()
+	for _, item := range []string{"Sacramento", "Austin"} {
+		if owner := snap.Board[item]; owner != "" {
+			t.Errorf("spoofed claim on %s credited to %s", item, owner)
+		}
+	}
+}
+
+func TestPhases_PauseRejectsClaimsAndFinishedGameLingers(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = testTriviaPath
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	clk := clock.NewFake()
+	globalState.SetClock(clk)
+	linger := 1
+	m := globalState.CreateGame(state.GameOptions{Title: "US Capitals", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME, LingerTime: &linger})
+	if m == nil {
+		t.Fatal("Create failed")
+	}
+
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, globalState)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	conn := dialPlayer(t, server.URL, m.Code, "LeBron")
+	defer conn.Close()
+	runFor(m, clk, 0)
+
+	// claims in the lobby are rejected
+	if err := conn.WriteJSON(map[string]string{"Item": "Boise"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if event := readUntil(t, conn, "Guess"); event.Outcome != protocol.GuessNotRunning {
+		t.Fatalf("outcome = %s, want %s", event.Outcome, protocol.GuessNotRunning)
+	}
+
+	if err := m.Command(game.CommandStart); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if event := readUntil(t, conn, "Phase"); event.Phase != game.PhaseRunning {
+		t.Fatalf("phase = %s, want running", event.Phase)
+	}
+	readUntil(t, conn, "Start")
+
+	if err := m.Command(game.CommandPause); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if event := readUntil(t, conn, "Phase"); event.Phase != game.PhasePaused {
+		t.Fatalf("phase = %s, want paused", event.Phase)
+	}
+	if err := conn.WriteJSON(map[string]string{"Item": "Boise"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if event := readUntil(t, conn, "Guess"); event.Outcome != protocol.GuessNotRunning {
+		t.Fatalf("outcome = %s, want %s", event.Outcome, protocol.GuessNotRunning)
+	}
+
+	if err := m.Command(game.CommandResume); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if err := conn.WriteJSON(map[string]string{"Item": "Boise"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	readUntil(t, conn, "Claimed")
+	if snap, _ := m.Snapshot(); snap.Board["Boise"] != "LeBron" {
+		t.Fatalf("Boise claimed by %q after resume, want LeBron", snap.Board["Boise"])
+	}
+
+	if err := m.Command(game.CommandEnd); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	readUntil(t, conn, "Leaderboard")
+	clk.Advance(time.Second)
+	select {
+	case <-m.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("finished game did not close after its linger time")
+	}
 }
@@ -0,0 +1,97 @@
+package gameflow
+
+import (
+	game "server/game"
+	protocol "server/protocol"
+	test "server/tst"
+	"testing"
+	"time"
+)
+
+// guess claims item as username and returns the Guess event that answers it.
+func guess(t *testing.T, m *game.Manager, p *game.Player, id, item string) game.GameEvent {
+	t.Helper()
+	m.Claim(game.PlayerRequest{Username: p.Username, Item: item, ID: id})
+	m.Snapshot() // the claim has been handled once this returns
+	guesses := ofType(drain(p), "Guess")
+	if len(guesses) != 1 {
+		t.Fatalf("%s guessed %q: got %d Guess events, want 1", p.Username, item, len(guesses))
+	}
+	if guesses[0].RequestID != id {
+		t.Errorf("%s guessed %q: RequestID = %q, want %q", p.Username, item, guesses[0].RequestID, id)
+	}
+	return guesses[0]
+}
+
+func TestGuess_Outcomes(t *testing.T) {
+	m, clk, players := newTimedGame(t, "LeBron", "Steph")
+	lebron, steph := players[0], players[1]
+
+	if got := guess(t, m, lebron, "1", "Boise"); got.Outcome != protocol.GuessNotRunning {
+		t.Errorf("in the lobby: outcome = %s, want %s", got.Outcome, protocol.GuessNotRunning)
+	}
+	clk.Advance(test.LOBBY_TIME * time.Second)
+
+	if got := guess(t, m, lebron, "2", "boise"); got.Outcome != protocol.GuessClaimed || got.Item != "Boise" {
+		t.Errorf("first guess = %s on %q, want %s on Boise", got.Outcome, got.Item, protocol.GuessClaimed)
+	}
+	got := guess(t, m, steph, "3", "Boise")
+	if got.Outcome != protocol.GuessAlreadyClaimed || got.Player != lebron {
+		t.Errorf("second guess = %s by %v, want %s by LeBron", got.Outcome, got.Player, protocol.GuessAlreadyClaimed)
+	}
+	if got := guess(t, m, steph, "4", "Portland"); got.Outcome != protocol.GuessNotOnBoard {
+		t.Errorf("wrong guess = %s, want %s", got.Outcome, protocol.GuessNotOnBoard)
+	}
+}
+
+func TestGuess_RateLimited(t *testing.T) {
+	m, clk, players := newTimedGame(t, "LeBron")
+	lebron := players[0]
+	clk.Advance(test.LOBBY_TIME * time.Second)
+
+	for range m.Options().GuessRate {
+		if got := guess(t, m, lebron, "", "Portland"); got.Outcome != protocol.GuessNotOnBoard {
+			t.Fatalf("outcome = %s, want %s", got.Outcome, protocol.GuessNotOnBoard)
+		}
+	}
+	if got := guess(t, m, lebron, "", "Boise"); got.Outcome != protocol.GuessRateLimited {
+		t.Fatalf("guess over the limit = %s, want %s", got.Outcome, protocol.GuessRateLimited)
+	}
+	if snap, _ := m.Snapshot(); snap.Board["Boise"] != "" {
+		t.Error("a rate-limited guess claimed a square")
+	}
+
+	// the limit is per second
+	clk.Advance(time.Second)
+	if got := guess(t, m, lebron, "", "Boise"); got.Outcome != protocol.GuessClaimed {
+		t.Errorf("guess a second later = %s, want %s", got.Outcome, protocol.GuessClaimed)
+	}
+}
+
+func TestGuess_V2EchoesID(t *testing.T) {
+	m, clk, url := setupServer(t)
+	lebron, _ := dialVersion(t, url, m.Code, "LeBron", "2")
+	defer lebron.Close()
+	steph, _ := dialVersion(t, url, m.Code, "Steph", "2")
+	defer steph.Close()
+
+	runFor(m, clk, test.LOBBY_TIME)
+	readMessage(t, lebron, protocol.TypeStart, &protocol.Start{})
+	if err := lebron.WriteJSON(protocol.Claim{Type: protocol.TypeClaim, ID: "a1", Item: "Boise"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var got protocol.Guess
+	readMessage(t, lebron, protocol.TypeGuess, &got)
+	if want := (protocol.Guess{Type: protocol.TypeGuess, ID: "a1", Outcome: protocol.GuessClaimed, Item: "Boise"}); got != want {
+		t.Errorf("guess = %+v, want %+v", got, want)
+	}
+
+	readMessage(t, steph, protocol.TypeClaimed, &protocol.Claimed{})
+	if err := steph.WriteJSON(protocol.Claim{Type: protocol.TypeClaim, ID: "b1", Item: "boise"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	readMessage(t, steph, protocol.TypeGuess, &got)
+	if want := (protocol.Guess{Type: protocol.TypeGuess, ID: "b1", Outcome: protocol.GuessAlreadyClaimed, Item: "Boise", ClaimedBy: "LeBron"}); got != want {
+		t.Errorf("guess = %+v, want %+v", got, want)
+	}
+}
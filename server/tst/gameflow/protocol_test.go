@@ -0,0 +1,232 @@
+package gameflow
+
+import (
+	"encoding/json"
+	game "server/game"
+	protocol "server/protocol"
+	test "server/tst"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialVersion connects as user asking for protocol version v and returns
+// the connection and the first message it got.
+func dialVersion(t *testing.T, serverURL, code, user, v string) (*websocket.Conn, map[string]any) {
+	t.Helper()
+	wsURL := "ws" + strings.TrimPrefix(serverURL, "http") + "/ws?game=" + code + "&user=" + user + "&v=" + v
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("WebSocket dial %s: %v", user, err)
+	}
+	var first map[string]any
+	if err := conn.ReadJSON(&first); err != nil {
+		conn.Close()
+		t.Fatalf("read first message: %v", err)
+	}
+	return conn, first
+}
+
+// readMessage reads raw messages until one has the given protocol type.
+func readMessage(t *testing.T, conn *websocket.Conn, msgType protocol.MessageType, v any) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", msgType, err)
+		}
+		var envelope struct {
+			Type protocol.MessageType `json:"type"`
+		}
+		json.Unmarshal(data, &envelope)
+		if envelope.Type == msgType {
+			if err := json.Unmarshal(data, v); err != nil {
+				t.Fatalf("decode %s: %v", msgType, err)
+			}
+			return
+		}
+	}
+}
+
+func TestProtocol_V2MessagesAreTyped(t *testing.T) {
+	m, clk, url := setupServer(t)
+	conn, first := dialVersion(t, url, m.Code, "LeBron", "2")
+	defer conn.Close()
+	if first["type"] != string(protocol.TypeWelcome) || first["version"] != float64(2) || first["token"] == "" {
+		t.Fatalf("first message = %v, want a version 2 welcome with a token", first)
+	}
+
+	var snap protocol.Snapshot
+	readMessage(t, conn, protocol.TypeSnapshot, &snap)
+	if snap.Phase != string(game.PhaseLobby) || len(snap.Board) == 0 || snap.Board["Boise"] != "" {
+		t.Errorf("snapshot = %+v, want an unclaimed lobby board", snap)
+	}
+
+	runFor(m, clk, test.LOBBY_TIME)
+	readMessage(t, conn, protocol.TypeStart, &protocol.Start{})
+	if err := conn.WriteJSON(protocol.Claim{Type: protocol.TypeClaim, Item: "boise"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var claimed protocol.Claimed
+	readMessage(t, conn, protocol.TypeClaimed, &claimed)
+	if claimed != (protocol.Claimed{Type: protocol.TypeClaimed, Seq: 1, Item: "Boise", Player: "LeBron"}) {
+		t.Errorf("claimed = %+v, want Boise by LeBron with seq 1", claimed)
+	}
+
+	if err := conn.WriteJSON(map[string]string{"type": "shout"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var bad protocol.Error
+	readMessage(t, conn, protocol.TypeError, &bad)
+	if bad.Code != protocol.ErrBadMessage {
+		t.Errorf("unknown message type: code = %q, want %q", bad.Code, protocol.ErrBadMessage)
+	}
+
+	if err := conn.WriteJSON(protocol.Resync{Type: protocol.TypeResync}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	readMessage(t, conn, protocol.TypeSnapshot, &snap)
+	if snap.Seq != 1 || snap.Board["Boise"] != "LeBron" {
+		t.Errorf("resync snapshot = %+v, want seq 1 with Boise claimed by LeBron", snap)
+	}
+}
+
+func TestProtocol_HandshakeErrorsHaveCodes(t *testing.T) {
+	m, _, url := setupServer(t)
+	lebron := dialPlayer(t, url, m.Code, "LeBron")
+	defer lebron.Close()
+
+	cases := []struct {
+		code, user, v string
+		want          protocol.ErrorCode
+	}{
+		{m.Code, "Steph", "0", protocol.ErrUnsupportedVersion},
+		{"", "Steph", "2", protocol.ErrMissingParams},
+		{"ZZZZZZ", "Steph", "2", protocol.ErrGameNotFound},
+		{m.Code, "LeBron", "2", protocol.ErrUsernameTaken},
+	}
+	for _, c := range cases {
+		conn, first := dialVersion(t, url, c.code, c.user, c.v)
+		conn.Close()
+		if first["type"] != string(protocol.TypeError) || first["code"] != string(c.want) || first["message"] == "" {
+			t.Errorf("game=%q user=%q v=%q: got %v, want a %s error", c.code, c.user, c.v, first, c.want)
+		}
+	}
+}
+
+// baselineEvent is what the original client reads from a legacy connection.
+type baselineEvent struct {
+	Type     string
+	TimeLeft int
+	State    map[string]*struct {
+		Username string `json:"username"`
+		Color    string `json:"color"`
+	}
+	Leaderboard []struct {
+		Username string `json:"username"`
+		Color    string `json:"color"`
+		Correct  int    `json:"correct"`
+	}
+}
+
+// readBoardUntil reads legacy events until a Board satisfies done.
+func readBoardUntil(t *testing.T, conn *websocket.Conn, done func(baselineEvent) bool) baselineEvent {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		var event baselineEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("waiting for a Board: %v", err)
+		}
+		if event.Type == "Board" && done(event) {
+			return event
+		}
+	}
+}
+
+// owner returns who holds item on a legacy board, or "".
+func owner(event baselineEvent, item string) string {
+	if p := event.State[item]; p != nil {
+		return p.Username
+	}
+	return ""
+}
+
+func TestProtocol_LegacyClientPlaysThrough(t *testing.T) {
+	m, _, url := setupServer(t)
+	lebron := dialPlayer(t, url, m.Code, "LeBron")
+	defer lebron.Close()
+	steph := dialPlayer(t, url, m.Code, "Steph")
+	defer steph.Close()
+
+	snap, _ := m.Snapshot()
+	joined := readBoardUntil(t, lebron, func(baselineEvent) bool { return true })
+	if len(joined.State) != len(snap.Board) || owner(joined, "Sacramento") != "" {
+		t.Fatalf("board on joining has %d squares, want %d empty ones", len(joined.State), len(snap.Board))
+	}
+	if err := m.Command(game.CommandStart); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	readUntil(t, lebron, "Start")
+	readUntil(t, steph, "Start")
+
+	lebron.WriteJSON(map[string]string{"Item": "Sacramento"})
+	readBoardUntil(t, steph, func(e baselineEvent) bool { return owner(e, "Sacramento") == "LeBron" })
+	steph.WriteJSON(map[string]string{"Item": "Boise"})
+	board := readBoardUntil(t, lebron, func(e baselineEvent) bool { return owner(e, "Boise") == "Steph" })
+	if owner(board, "Sacramento") != "LeBron" {
+		t.Errorf("Sacramento belongs to %q after Steph's claim, want LeBron", owner(board, "Sacramento"))
+	}
+
+	// a client that missed the board asks for it again, as Game.tsx does on mount
+	steph.WriteJSON(map[string]string{"Item": game.ResyncItem})
+	board = readBoardUntil(t, steph, func(e baselineEvent) bool { return owner(e, "Boise") == "Steph" })
+	if owner(board, "Sacramento") != "LeBron" {
+		t.Errorf("after RESYNC: Sacramento belongs to %q, want LeBron", owner(board, "Sacramento"))
+	}
+
+	if err := m.Command(game.CommandEnd); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	var end baselineEvent
+	lebron.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for end.Type != "Leaderboard" {
+		if err := lebron.ReadJSON(&end); err != nil {
+			t.Fatalf("waiting for the Leaderboard: %v", err)
+		}
+	}
+	if len(end.Leaderboard) != 2 || end.Leaderboard[0].Correct != 1 || end.Leaderboard[1].Correct != 1 {
+		t.Errorf("leaderboard = %+v, want LeBron and Steph with one square each", end.Leaderboard)
+	}
+}
+
+func TestProtocol_LegacyEventsHaveOnlyBaselineFields(t *testing.T) {
+	m, clk, url := setupServer(t)
+	conn := dialPlayer(t, url, m.Code, "LeBron")
+	defer conn.Close()
+	runFor(m, clk, 1)
+
+	baseline := map[string]bool{"Type": true, "State": true, "TimeLeft": true, "Winner": true, "Players": true, "Leaderboard": true}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for seen := map[string]bool{}; !seen["Time"] || !seen["Players"] || !seen["Board"]; {
+		var event map[string]any
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("read: %v (seen %v)", err, seen)
+		}
+		eventType, _ := event["Type"].(string)
+		seen[eventType] = true
+		if eventType == "Snapshot" || eventType == "Phase" {
+			continue // newer event types, which the original client ignores
+		}
+		for field := range event {
+			if !baseline[field] {
+				t.Errorf("%s event has field %s, which the original client doesn't know", eventType, field)
+			}
+		}
+	}
+}
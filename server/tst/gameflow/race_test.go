@@ -0,0 +1,154 @@
+package gameflow
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	clock "server/clock"
+	game "server/game"
+	gameinit "server/game-init"
+	"server/state"
+	test "server/tst"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Run with -race (make race): many players join, claim and poll at once, and
+// every square must end up credited exactly once.
+func TestConcurrentJoinsAndClaims(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = testTriviaPath
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	globalState.SetClock(clock.NewFake()) // the game only ends when every square is taken
+	noLimit := 0                          // the fake clock never leaves the first second
+	m := globalState.CreateGame(state.GameOptions{Title: "US Capitals", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME, GuessRate: &noLimit})
+	if m == nil {
+		t.Fatal("Create failed")
+	}
+	code := m.Code
+
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, globalState)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	snap, _ := m.Snapshot()
+	items := make([]string, 0, len(snap.Board))
+	for item := range snap.Board {
+		items = append(items, item)
+	}
+
+	// stays connected so the finished game doesn't close before it's checked
+	observer := dialPlayer(t, server.URL, code, "observer")
+	defer observer.Close()
+
+	const players = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, players)
+	for i := range players {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- playAndClaim(server.URL, code, fmt.Sprintf("player%d", i), func(j int) bool {
+				// every square is contested by two players
+				return j%players == i || j%players == (i+1)%players
+			}, items)
+		}()
+	}
+
+	// keep the HTTP-side readers busy while the game runs
+	stop := make(chan struct{})
+	var pollers sync.WaitGroup
+	pollers.Add(1)
+	go func() {
+		defer pollers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				m.Snapshot()
+				m.HasPlayer("player0")
+			}
+		}
+	}()
+
+	for {
+		if s, _ := m.Snapshot(); len(s.Players) == players+1 {
+			break
+		}
+	}
+	go m.Run()
+	if err := m.Command(game.CommandStart); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	wg.Wait()
+	close(stop)
+	pollers.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	final, _ := m.Snapshot()
+	total := 0
+	for _, p := range final.Players {
+		total += p.Correct
+	}
+	for item, owner := range final.Board {
+		if owner == "" {
+			t.Errorf("%s was never claimed", item)
+		}
+	}
+	if total != len(items) {
+		t.Errorf("players were credited %d squares, want %d", total, len(items))
+	}
+}
+
+// playAndClaim joins as user, waits for the game to start, claims the items
+// picked by want, and waits for the leaderboard. It runs off the test
+// goroutine, so it reports errors instead of failing the test.
+func playAndClaim(serverURL, code, user string, want func(int) bool, items []string) error {
+	wsURL := "ws" + strings.TrimPrefix(serverURL, "http") + "/ws?game=" + code + "&user=" + user
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("%s: dial: %w", user, err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	var joined map[string]string
+	if err := conn.ReadJSON(&joined); err != nil || joined["type"] != "success" {
+		return fmt.Errorf("%s: join: got %v, %v", user, joined, err)
+	}
+	waitFor := func(eventType string) error {
+		for {
+			var event game.GameEvent
+			if err := conn.ReadJSON(&event); err != nil {
+				return fmt.Errorf("%s: waiting for %s: %w", user, eventType, err)
+			}
+			if event.Type == eventType {
+				return nil
+			}
+		}
+	}
+	if err := waitFor("Start"); err != nil {
+		return err
+	}
+	for j, item := range items {
+		if !want(j) {
+			continue
+		}
+		if err := conn.WriteJSON(map[string]string{"Item": item}); err != nil {
+			return fmt.Errorf("%s: claim %s: %w", user, item, err)
+		}
+	}
+	return waitFor("Leaderboard")
+}
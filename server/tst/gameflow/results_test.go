@@ -0,0 +1,138 @@
+package gameflow
+
+import (
+	"reflect"
+	clock "server/clock"
+	game "server/game"
+	protocol "server/protocol"
+	test "server/tst"
+	"testing"
+	"time"
+)
+
+// playResults plays a five-square game on a fake clock in which each player
+// claims their items one second apart, in turn, and returns the Leaderboard
+// event every player got.
+func playResults(t *testing.T, tieBreaker game.TieBreaker, claims [][2]string) []game.GameEvent {
+	t.Helper()
+	clk := clock.NewFake()
+	opts := game.DefaultOptions()
+	opts.TieBreaker = tieBreaker
+	m := game.NewManager("Five Squares", "RESULT", test.LOBBY_TIME, test.GAME_TIME, clk, opts)
+	m.SetAnswers([]game.Answer{{Item: "Boise"}, {Item: "Salem"}, {Item: "Helena"}, {Item: "Olympia"}, {Item: "Juneau"}}, game.StrictnessNormal)
+	var players []*game.Player
+	for _, name := range []string{"Steph", "LeBron", "Kyrie", "Zion"} {
+		p, _, err := m.Join(name, "")
+		if err != nil {
+			t.Fatalf("join %s: %v", name, err)
+		}
+		players = append(players, p)
+	}
+	go m.Run()
+	clk.WaitForTickers(1)
+	clk.Advance(test.LOBBY_TIME * time.Second)
+	for _, c := range claims {
+		m.Claim(game.PlayerRequest{Username: c[0], Item: c[1]})
+		m.Snapshot()
+		clk.Advance(time.Second)
+	}
+	if err := m.Command(game.CommandEnd); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	var boards []game.GameEvent
+	for _, p := range players {
+		got := ofType(drain(p), "Leaderboard")
+		if len(got) != 1 {
+			t.Fatalf("%s got %d leaderboards, want 1", p.Username, len(got))
+		}
+		boards = append(boards, got[0])
+	}
+	return boards
+}
+
+type standing struct {
+	Rank     int
+	Username string
+	Items    []string
+}
+
+func standings(event game.GameEvent) []standing {
+	var out []standing
+	for _, e := range event.Leaderboard {
+		out = append(out, standing{e.Rank, e.Username, e.Items})
+	}
+	return out
+}
+
+var tiedClaims = [][2]string{
+	{"LeBron", "Boise"},
+	{"Steph", "Salem"},
+	{"Steph", "Helena"},
+	{"LeBron", "Olympia"},
+}
+
+func TestResults_EveryPlayerRankedWithSharedTies(t *testing.T) {
+	boards := playResults(t, game.TieBreakShared, tiedClaims)
+	want := []standing{
+		{1, "LeBron", []string{"Boise", "Olympia"}},
+		{1, "Steph", []string{"Salem", "Helena"}},
+		{3, "Kyrie", []string{}},
+		{3, "Zion", []string{}},
+	}
+	for _, b := range boards {
+		if got := standings(b); !reflect.DeepEqual(got, want) {
+			t.Errorf("standings = %v, want %v", got, want)
+		}
+		if !reflect.DeepEqual(b.Unfound, []string{"Juneau"}) {
+			t.Errorf("unfound = %v, want [Juneau]", b.Unfound)
+		}
+	}
+}
+
+func TestResults_LastClaimBreaksTies(t *testing.T) {
+	boards := playResults(t, game.TieBreakLastClaim, tiedClaims)
+	want := []standing{
+		{1, "Steph", []string{"Salem", "Helena"}},
+		{2, "LeBron", []string{"Boise", "Olympia"}},
+		{3, "Kyrie", []string{}},
+		{3, "Zion", []string{}},
+	}
+	if got := standings(boards[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("standings = %v, want %v", got, want)
+	}
+}
+
+func TestResults_V2LeaderboardAndSnapshot(t *testing.T) {
+	m, clk, url := setupServer(t)
+	conn, _ := dialVersion(t, url, m.Code, "LeBron", "2")
+	defer conn.Close()
+	runFor(m, clk, test.LOBBY_TIME)
+	readMessage(t, conn, protocol.TypeStart, &protocol.Start{})
+	if err := conn.WriteJSON(protocol.Claim{Type: protocol.TypeClaim, Item: "Boise"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	readMessage(t, conn, protocol.TypeClaimed, &protocol.Claimed{})
+	if err := m.Command(game.CommandEnd); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+
+	var board protocol.Leaderboard
+	readMessage(t, conn, protocol.TypeLeaderboard, &board)
+	want := []protocol.LeaderboardEntry{{Rank: 1, Username: "LeBron", Color: board.Entries[0].Color, Correct: 1, Items: []string{"Boise"}}}
+	if !reflect.DeepEqual(board.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", board.Entries, want)
+	}
+	snap, _ := m.Snapshot()
+	if len(board.Unfound) != len(snap.Board)-1 {
+		t.Errorf("%d unfound, want %d", len(board.Unfound), len(snap.Board)-1)
+	}
+
+	if err := conn.WriteJSON(protocol.Resync{Type: protocol.TypeResync}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var final protocol.Snapshot
+	readMessage(t, conn, protocol.TypeSnapshot, &final)
+	if !reflect.DeepEqual(final.Leaderboard, board.Entries) || !reflect.DeepEqual(final.Unfound, board.Unfound) {
+		t.Errorf("finished snapshot has %+v and %v, want the leaderboard's", final.Leaderboard, final.Unfound)
+	}
+}
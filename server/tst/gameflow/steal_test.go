@@ -0,0 +1,108 @@
+package gameflow
+
+import (
+	game "server/game"
+	protocol "server/protocol"
+	test "server/tst"
+	"testing"
+	"time"
+)
+
+// stealGame is a two-square steal mode game with the given rules, past the lobby.
+func stealGame(cooldown, maxSteals int) gameSetup {
+	return gameSetup{
+		options: func(o *game.Options) {
+			o.Steal, o.StealCooldown, o.MaxSteals = true, cooldown, maxSteals
+		},
+		running: true,
+	}
+}
+
+func correctOf(snap game.Snapshot) map[string]int {
+	correct := make(map[string]int)
+	for _, p := range snap.Players {
+		correct[p.Username] = p.Correct
+	}
+	return correct
+}
+
+func TestSteal_TakesSquareAfterCooldown(t *testing.T) {
+	m, clk, players := newGame(t, stealGame(5, 2), "LeBron", "Steph")
+	lebron, steph := players[0], players[1]
+	guess(t, m, lebron, "1", "Boise")
+
+	if got := guess(t, m, steph, "2", "Boise"); got.Outcome != protocol.GuessCooldown || got.Player != lebron {
+		t.Errorf("during the cooldown: outcome = %s by %v, want %s by LeBron", got.Outcome, got.Player, protocol.GuessCooldown)
+	}
+	clk.Advance(5 * time.Second)
+	if got := guess(t, m, steph, "3", "Boise"); got.Outcome != protocol.GuessStolen || got.Item != "Boise" {
+		t.Fatalf("after the cooldown: outcome = %s on %q, want %s on Boise", got.Outcome, got.Item, protocol.GuessStolen)
+	}
+
+	snap, _ := m.Snapshot()
+	if snap.Board["Boise"] != "Steph" {
+		t.Errorf("Boise belongs to %q, want Steph", snap.Board["Boise"])
+	}
+	if got := correctOf(snap); got["LeBron"] != 0 || got["Steph"] != 1 {
+		t.Errorf("correct = %v, want LeBron 0 and Steph 1", got)
+	}
+	stolen := ofType(drain(lebron), "Stolen")
+	if len(stolen) != 1 {
+		t.Fatalf("LeBron got %d Stolen events, want 1", len(stolen))
+	}
+	if e := stolen[0]; e.Seq != 2 || e.Item != "Boise" || e.Player.Username != "Steph" || e.From.Username != "LeBron" {
+		t.Errorf("stolen = seq %d, %q to %v from %v; want seq 2, Boise to Steph from LeBron", e.Seq, e.Item, e.Player, e.From)
+	}
+}
+
+func TestSteal_SquareLocksAfterMaxSteals(t *testing.T) {
+	m, _, players := newGame(t, stealGame(0, 1), "LeBron", "Steph")
+	lebron, steph := players[0], players[1]
+	guess(t, m, lebron, "1", "Boise")
+	if got := guess(t, m, steph, "2", "Boise"); got.Outcome != protocol.GuessStolen {
+		t.Fatalf("first steal = %s, want %s", got.Outcome, protocol.GuessStolen)
+	}
+	if got := guess(t, m, lebron, "3", "Boise"); got.Outcome != protocol.GuessLocked || got.Player != steph {
+		t.Errorf("second steal = %s by %v, want %s by Steph", got.Outcome, got.Player, protocol.GuessLocked)
+	}
+	if got := guess(t, m, steph, "4", "Boise"); got.Outcome != protocol.GuessAlreadyClaimed {
+		t.Errorf("owner's guess = %s, want %s", got.Outcome, protocol.GuessAlreadyClaimed)
+	}
+}
+
+func TestSteal_OffByDefault(t *testing.T) {
+	m, clk, players := newTimedGame(t, "LeBron", "Steph")
+	clk.Advance(test.LOBBY_TIME * time.Second)
+	guess(t, m, players[0], "1", "Boise")
+	clk.Advance((test.GAME_TIME - 1) * time.Second)
+	if got := guess(t, m, players[1], "2", "Boise"); got.Outcome != protocol.GuessAlreadyClaimed {
+		t.Errorf("outcome = %s, want %s", got.Outcome, protocol.GuessAlreadyClaimed)
+	}
+}
+
+func TestSteal_RecordedInResults(t *testing.T) {
+	m, _, players := newGame(t, stealGame(0, 2), "LeBron", "Steph")
+	guess(t, m, players[0], "1", "Boise")
+	guess(t, m, players[1], "2", "Boise")
+	guess(t, m, players[0], "3", "Salem") // fills the board
+
+	results, ok := m.Results()
+	if !ok {
+		t.Fatal("no results once the board is full")
+	}
+	if s := results.Settings; !s.Steal || s.StealCooldown != 0 || s.MaxSteals != 2 {
+		t.Errorf("settings = %+v, want steal mode with no cooldown and 2 steals", s)
+	}
+	var steals []game.LogEntry
+	for _, e := range results.Events {
+		if e.Type == game.LogSteal {
+			steals = append(steals, e)
+		}
+	}
+	if len(steals) != 1 || steals[0].Player != "Steph" || steals[0].From != "LeBron" || steals[0].Item != "Boise" {
+		t.Errorf("steals = %+v, want Steph taking Boise from LeBron", steals)
+	}
+	if got := results.Standings; got[0].Count != 1 || got[1].Count != 1 {
+		t.Errorf("standings = %+v, want one square each", got)
+	}
+}
@@ -0,0 +1,123 @@
+package gameflow
+
+import (
+	game "server/game"
+	test "server/tst"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialSession joins the game as user with a session token ("" for a new
+// player) and returns the connection and the token to reconnect with.
+func dialSession(t *testing.T, serverURL, code, user, token string) (*websocket.Conn, string) {
+	t.Helper()
+	wsURL := "ws" + strings.TrimPrefix(serverURL, "http") + "/ws?game=" + code + "&user=" + user + "&token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("WebSocket dial %s: %v", user, err)
+	}
+	var successMsg map[string]string
+	if err := conn.ReadJSON(&successMsg); err != nil || successMsg["type"] != "success" {
+		conn.Close()
+		t.Fatalf("join %s: got %v, %v", user, successMsg, err)
+	}
+	return conn, successMsg["token"]
+}
+
+func TestSync_ClaimsAreNumberedInOrder(t *testing.T) {
+	m, clk, players := newTimedGame(t, "LeBron")
+	clk.Advance(time.Duration(test.LOBBY_TIME) * time.Second)
+
+	m.Claim(game.PlayerRequest{Username: "LeBron", Item: "Boise"})
+	m.Claim(game.PlayerRequest{Username: "LeBron", Item: "Boise"}) // already claimed: no event
+	m.Claim(game.PlayerRequest{Username: "LeBron", Item: "Salem"})
+	waitForSnapshot(t, m, func(s game.Snapshot) bool { return s.Phase == game.PhaseFinished })
+
+	claims := ofType(drain(players[0]), "Claimed")
+	if len(claims) != 2 {
+		t.Fatalf("%d Claimed events, want 2", len(claims))
+	}
+	for i, want := range []string{"Boise", "Salem"} {
+		if claims[i].Item != want || claims[i].Seq != i+1 || claims[i].Player.Username != "LeBron" {
+			t.Errorf("claim %d = %s by %s (seq %d), want %s by LeBron (seq %d)",
+				i, claims[i].Item, claims[i].Player.Username, claims[i].Seq, want, i+1)
+		}
+	}
+}
+
+func TestSync_ResyncInLobbyAndWhilePaused(t *testing.T) {
+	m, clk, players := newTimedGame(t, "LeBron")
+	p := players[0]
+
+	m.Claim(game.PlayerRequest{Username: "LeBron", Item: game.ResyncItem})
+	waitForSnapshot(t, m, func(game.Snapshot) bool { return true })
+	snaps := ofType(drain(p), "Snapshot")
+	if len(snaps) != 1 || snaps[0].Seq != 0 || snaps[0].Phase != game.PhaseLobby {
+		t.Fatalf("lobby resync = %+v, want one Snapshot with seq 0", snaps)
+	}
+
+	clk.Advance(time.Duration(test.LOBBY_TIME) * time.Second)
+	m.Claim(game.PlayerRequest{Username: "LeBron", Item: "Boise"})
+	waitForSnapshot(t, m, func(s game.Snapshot) bool { return s.Board["Boise"] == "LeBron" })
+	if err := m.Command(game.CommandPause); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	drain(p)
+
+	m.Claim(game.PlayerRequest{Username: "LeBron", Item: game.ResyncItem})
+	waitForSnapshot(t, m, func(game.Snapshot) bool { return true })
+	events := drain(p)
+	if errs := ofType(events, "Error"); len(errs) != 0 {
+		t.Errorf("resync while paused was rejected: %s", errs[0].Error)
+	}
+	snaps = ofType(events, "Snapshot")
+	if len(snaps) != 1 || snaps[0].Seq != 1 || snaps[0].State["Boise"] == nil {
+		t.Fatalf("paused resync = %+v, want one Snapshot with seq 1 and Boise claimed", snaps)
+	}
+}
+
+func TestSync_ReconnectSnapshotThenNextClaim(t *testing.T) {
+	m, clk, url := setupServer(t)
+	lebron := dialPlayer(t, url, m.Code, "LeBron")
+	defer lebron.Close()
+	steph, token := dialSession(t, url, m.Code, "Steph", "")
+	runFor(m, clk, test.LOBBY_TIME)
+	readUntil(t, steph, "Start")
+
+	if err := steph.WriteJSON(map[string]string{"Item": "Sacramento"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	readUntil(t, steph, "Claimed")
+	steph.Close()
+	waitForSnapshot(t, m, func(s game.Snapshot) bool {
+		for _, p := range s.Players {
+			if p.Username == "Steph" {
+				return !p.Connected
+			}
+		}
+		return false
+	})
+
+	// a claim Steph misses while away
+	if err := lebron.WriteJSON(map[string]string{"Item": "Olympia"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	waitForSnapshot(t, m, func(s game.Snapshot) bool { return s.Board["Olympia"] == "LeBron" })
+
+	steph, _ = dialSession(t, url, m.Code, "Steph", token)
+	defer steph.Close()
+	snap := readUntil(t, steph, "Snapshot")
+	if snap.Seq != 2 || snap.State["Olympia"] == nil {
+		t.Fatalf("reconnect snapshot has seq %d, want 2 with Olympia claimed", snap.Seq)
+	}
+
+	if err := lebron.WriteJSON(map[string]string{"Item": "Boise"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if next := readUntil(t, steph, "Claimed"); next.Seq != snap.Seq+1 || next.Item != "Boise" {
+		t.Errorf("after the snapshot got %s (seq %d), want Boise (seq %d)", next.Item, next.Seq, snap.Seq+1)
+	}
+}
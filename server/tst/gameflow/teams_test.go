@@ -0,0 +1,136 @@
+package gameflow
+
+import (
+	"reflect"
+	game "server/game"
+	protocol "server/protocol"
+	test "server/tst"
+	"testing"
+	"time"
+)
+
+// redVsBlue is a four-square Red v Blue game.
+var redVsBlue = gameSetup{squares: []string{"Boise", "Salem", "Helena", "Olympia"}, teams: []string{"Red", "Blue"}}
+
+func teamsOf(snap game.Snapshot) map[string]string {
+	teams := make(map[string]string)
+	for _, p := range snap.Players {
+		teams[p.Username] = p.Team
+	}
+	return teams
+}
+
+func TestTeams_JoinersAreBalancedAndCanSwitchInTheLobby(t *testing.T) {
+	m, clk, players := newGame(t, redVsBlue, "LeBron", "Steph", "Kyrie", "Zion")
+	snap, _ := m.Snapshot()
+	want := map[string]string{"LeBron": "Red", "Steph": "Blue", "Kyrie": "Red", "Zion": "Blue"}
+	if got := teamsOf(snap); !reflect.DeepEqual(got, want) {
+		t.Fatalf("teams = %v, want %v", got, want)
+	}
+	if len(snap.Teams) != 2 || snap.Teams[0].Color == snap.Teams[1].Color {
+		t.Errorf("snapshot teams = %+v, want Red and Blue in different colors", snap.Teams)
+	}
+
+	m.Claim(game.PlayerRequest{Username: "Kyrie", Item: game.TeamItem, Team: "Blue"})
+	m.Claim(game.PlayerRequest{Username: "Zion", Item: game.TeamItem, Team: "Green"})
+	snap, _ = m.Snapshot()
+	want["Kyrie"] = "Blue"
+	if got := teamsOf(snap); !reflect.DeepEqual(got, want) {
+		t.Errorf("after switching: teams = %v, want %v", got, want)
+	}
+	if errs := ofType(drain(players[3]), "Error"); len(errs) != 1 || errs[0].Code != protocol.ErrUnknownTeam {
+		t.Errorf("picking a missing team got %v, want one %s error", errs, protocol.ErrUnknownTeam)
+	}
+
+	// teams are fixed once the game starts
+	clk.Advance(test.LOBBY_TIME * time.Second)
+	drain(players[2])
+	m.Claim(game.PlayerRequest{Username: "Kyrie", Item: game.TeamItem, Team: "Red"})
+	snap, _ = m.Snapshot()
+	if team := teamsOf(snap)["Kyrie"]; team != "Blue" {
+		t.Errorf("Kyrie is on %s after the start, want Blue", team)
+	}
+	if errs := ofType(drain(players[2]), "Error"); len(errs) != 1 || errs[0].Code != protocol.ErrNotAccepted {
+		t.Errorf("switching after the start got %v, want one %s error", errs, protocol.ErrNotAccepted)
+	}
+}
+
+func TestTeams_StandingsRankTeamsWithMembers(t *testing.T) {
+	m, clk, players := newGame(t, redVsBlue, "LeBron", "Steph", "Kyrie")
+	clk.Advance(test.LOBBY_TIME * time.Second)
+	for _, c := range [][2]string{{"LeBron", "Boise"}, {"Steph", "Salem"}, {"Kyrie", "Helena"}} {
+		m.Claim(game.PlayerRequest{Username: c[0], Item: c[1]})
+	}
+	snap, _ := m.Snapshot()
+	wantBoard := map[string]string{"Boise": "Red", "Salem": "Blue", "Helena": "Red"}
+	if !reflect.DeepEqual(snap.BoardTeams, wantBoard) {
+		t.Errorf("board teams = %v, want %v", snap.BoardTeams, wantBoard)
+	}
+	if err := m.Command(game.CommandEnd); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+
+	boards := ofType(drain(players[0]), "Leaderboard")
+	if len(boards) != 1 {
+		t.Fatalf("got %d leaderboards, want 1", len(boards))
+	}
+	type member struct {
+		Username string
+		Count    int
+	}
+	type team struct {
+		Rank    int
+		Name    string
+		Count   int
+		Members []member
+	}
+	var got []team
+	for _, s := range boards[0].TeamBoard {
+		tm := team{Rank: s.Rank, Name: s.Name, Count: s.Count}
+		for _, e := range s.Members {
+			tm.Members = append(tm.Members, member{e.Username, e.Count})
+		}
+		got = append(got, tm)
+	}
+	want := []team{
+		{1, "Red", 2, []member{{"Kyrie", 1}, {"LeBron", 1}}},
+		{2, "Blue", 1, []member{{"Steph", 1}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("team standings = %+v, want %+v", got, want)
+	}
+	if results, _ := m.Results(); !reflect.DeepEqual(results.Teams, boards[0].TeamBoard) {
+		t.Error("results and leaderboard disagree on the team standings")
+	}
+}
+
+func TestTeams_V2PickTeamAndClaimed(t *testing.T) {
+	m, clk, url := setupServer(t)
+	m.SetTeams([]string{"Red", "Blue"})
+	conn, _ := dialVersion(t, url, m.Code, "LeBron", "2")
+	defer conn.Close()
+
+	var snap protocol.Snapshot
+	readMessage(t, conn, protocol.TypeSnapshot, &snap)
+	if len(snap.Teams) != 2 || snap.Players[0].Team != "Red" {
+		t.Fatalf("snapshot = %+v, want two teams with LeBron on Red", snap)
+	}
+	if err := conn.WriteJSON(protocol.PickTeam{Type: protocol.TypePickTeam, Team: "Blue"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var players protocol.Players
+	for players.Players == nil || players.Players[0].Team != "Blue" {
+		readMessage(t, conn, protocol.TypePlayers, &players)
+	}
+
+	runFor(m, clk, test.LOBBY_TIME)
+	readMessage(t, conn, protocol.TypeStart, &protocol.Start{})
+	if err := conn.WriteJSON(protocol.Claim{Type: protocol.TypeClaim, Item: "Boise"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var claimed protocol.Claimed
+	readMessage(t, conn, protocol.TypeClaimed, &claimed)
+	if claimed.Team != "Blue" {
+		t.Errorf("claimed = %+v, want it claimed for Blue", claimed)
+	}
+}
@@ -0,0 +1,178 @@
+package gameflow
+
+import (
+	clock "server/clock"
+	game "server/game"
+	protocol "server/protocol"
+	test "server/tst"
+	"testing"
+	"time"
+)
+
+// gameSetup is what a test changes about the game newGame starts.
+type gameSetup struct {
+	squares []string            // Boise and Salem if empty
+	teams   []string            // makes it a team game
+	options func(*game.Options) // changes the default settings
+	running bool                // advance past the lobby before returning
+}
+
+// newTimedGame starts a two-square game on a fake clock with players who
+// have no connection, so their events can be read with Pending.
+func newTimedGame(t *testing.T, usernames ...string) (*game.Manager, *clock.Fake, []*game.Player) {
+	t.Helper()
+	return newGame(t, gameSetup{}, usernames...)
+}
+
+// newGame is newTimedGame for a game set up differently.
+func newGame(t *testing.T, setup gameSetup, usernames ...string) (*game.Manager, *clock.Fake, []*game.Player) {
+	t.Helper()
+	clk := clock.NewFake()
+	opts := game.DefaultOptions()
+	if setup.options != nil {
+		setup.options(&opts)
+	}
+	m := game.NewManager("Timed Quiz", "TIMING", test.LOBBY_TIME, test.GAME_TIME, clk, opts)
+	squares := setup.squares
+	if len(squares) == 0 {
+		squares = []string{"Boise", "Salem"}
+	}
+	answers := make([]game.Answer, 0, len(squares))
+	for _, item := range squares {
+		answers = append(answers, game.Answer{Item: item})
+	}
+	m.SetAnswers(answers, game.StrictnessNormal)
+	if len(setup.teams) > 0 {
+		m.SetTeams(setup.teams)
+	}
+	players := make([]*game.Player, 0, len(usernames))
+	for _, name := range usernames {
+		p, _, err := m.Join(name, "")
+		if err != nil {
+			t.Fatalf("join %s: %v", name, err)
+		}
+		players = append(players, p)
+	}
+	go m.Run()
+	clk.WaitForTickers(1)
+	if setup.running {
+		clk.Advance(test.LOBBY_TIME * time.Second)
+	}
+	return m, clk, players
+}
+
+// drain returns every event queued for p.
+func drain(p *game.Player) []game.GameEvent {
+	return p.Pending()
+}
+
+func ofType(events []game.GameEvent, eventType string) []game.GameEvent {
+	var out []game.GameEvent
+	for _, e := range events {
+		if e.Type == eventType {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func phase(t *testing.T, m *game.Manager) game.Phase {
+	t.Helper()
+	snap, ok := m.Snapshot()
+	if !ok {
+		return game.PhaseClosed
+	}
+	return snap.Phase
+}
+
+func TestTiming_LobbyToLeaderboard(t *testing.T) {
+	m, clk, players := newTimedGame(t, "LeBron", "Steph")
+
+	clk.Advance(time.Duration(test.LOBBY_TIME-1) * time.Second)
+	if got := phase(t, m); got != game.PhaseCountdown {
+		t.Fatalf("1s before the lobby ends: phase = %s, want countdown", got)
+	}
+	clk.Advance(time.Second)
+	if got := phase(t, m); got != game.PhaseRunning {
+		t.Fatalf("when the lobby ends: phase = %s, want running", got)
+	}
+	if len(ofType(drain(players[0]), "Start")) != 1 {
+		t.Error("expected one Start event")
+	}
+
+	m.Claim(game.PlayerRequest{Username: "Steph", Item: "Salem"})
+	clk.Advance(time.Duration(test.GAME_TIME) * time.Second)
+	if got := phase(t, m); got != game.PhaseFinished {
+		t.Fatalf("when the game ends: phase = %s, want finished", got)
+	}
+	boards := ofType(drain(players[1]), "Leaderboard")
+	if len(boards) != 1 || boards[0].Leaderboard[0].Username != "Steph" {
+		t.Fatalf("leaderboard events = %+v, want one led by Steph", boards)
+	}
+
+	clk.Advance(time.Duration(m.Options().LingerTime) * time.Second)
+	select {
+	case <-m.Done():
+	case <-time.After(time.Second):
+		t.Fatal("game did not close after lingering")
+	}
+}
+
+func TestTiming_ClaimAtTimeZeroIsRejected(t *testing.T) {
+	m, clk, players := newTimedGame(t, "LeBron")
+	clk.Advance(time.Duration(test.LOBBY_TIME+test.GAME_TIME-1) * time.Second)
+
+	// with one second left the claim counts
+	m.Claim(game.PlayerRequest{Username: "LeBron", Item: "Boise"})
+	if snap, _ := m.Snapshot(); snap.TimeLeft != 1 || snap.Board["Boise"] != "LeBron" {
+		t.Fatalf("at T=1: TimeLeft = %d, Boise = %q; want 1, LeBron", snap.TimeLeft, snap.Board["Boise"])
+	}
+	drain(players[0])
+
+	// at zero the game is over
+	clk.Advance(time.Second)
+	m.Claim(game.PlayerRequest{Username: "LeBron", Item: "Salem"})
+	snap, _ := m.Snapshot()
+	if snap.TimeLeft != 0 || snap.Phase != game.PhaseFinished {
+		t.Fatalf("at T=0: TimeLeft = %d, phase = %s; want 0, finished", snap.TimeLeft, snap.Phase)
+	}
+	if snap.Board["Salem"] != "" {
+		t.Error("claim at T=0 was credited")
+	}
+	guesses := ofType(drain(players[0]), "Guess")
+	if len(guesses) != 1 || guesses[0].Outcome != protocol.GuessNotRunning {
+		t.Errorf("claim at T=0 got %v, want one %s Guess", guesses, protocol.GuessNotRunning)
+	}
+}
+
+func TestTiming_LastSquareAtExpiry(t *testing.T) {
+	for range 20 {
+		m, clk, players := newTimedGame(t, "LeBron")
+		clk.Advance(time.Duration(test.LOBBY_TIME+test.GAME_TIME-1) * time.Second)
+		m.Claim(game.PlayerRequest{Username: "LeBron", Item: "Boise"})
+
+		// the last square and the final tick arrive together; either may win
+		m.Claim(game.PlayerRequest{Username: "LeBron", Item: "Salem"})
+		clk.Advance(time.Second)
+
+		snap, _ := m.Snapshot()
+		if snap.Phase != game.PhaseFinished {
+			t.Fatalf("phase = %s, want finished", snap.Phase)
+		}
+		boards := ofType(drain(players[0]), "Leaderboard")
+		if len(boards) != 1 {
+			t.Fatalf("got %d leaderboards, want exactly 1", len(boards))
+		}
+		credited := 0
+		for _, owner := range snap.Board {
+			if owner == "LeBron" {
+				credited++
+			}
+		}
+		if got := boards[0].Leaderboard[0].Count; got != credited {
+			t.Fatalf("leaderboard says %d, board shows %d claimed", got, credited)
+		}
+		clk.Advance(time.Duration(m.Options().LingerTime) * time.Second)
+		<-m.Done()
+	}
+}
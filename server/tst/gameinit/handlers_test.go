@@ -11,6 +11,7 @@ import (
 	test "server/tst"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -86,6 +87,161 @@ func TestCreateHandler_Success(t *testing.T) {
 	}
 }
 
+func TestCreateHandler_LingerTime(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = "../../../trivia"
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	create := func(body string) (int, *game.Manager) {
+		req := httptest.NewRequest(http.MethodPost, "/create-game", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		gameinit.CreateHandler(globalState, rec, req)
+		var resp gameinit.CreateResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, globalState.GetGame(resp.Code)
+	}
+	for _, linger := range []string{"-1", "301"} {
+		if code, _ := create(`{"title": "US Capitals", "lingerTime": ` + linger + `}`); code != http.StatusBadRequest {
+			t.Errorf("lingerTime %s: status = %d, want 400", linger, code)
+		}
+	}
+	if code, m := create(`{"title": "US Capitals", "lingerTime": 0}`); code != http.StatusOK || m.Options().LingerTime != 0 {
+		t.Errorf("lingerTime 0: status = %d, want 200 and a game that closes without lingering", code)
+	}
+	if code, m := create(`{"title": "US Capitals"}`); code != http.StatusOK || m.Options().LingerTime != game.DefaultLingerTime {
+		t.Errorf("no lingerTime: status = %d, want 200 and the default linger", code)
+	}
+}
+
+func TestCreateHandler_TieBreaker(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = "../../../trivia"
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	cases := []struct {
+		body   string
+		status int
+		want   game.TieBreaker
+	}{
+		{`{"title": "US Capitals"}`, http.StatusOK, game.TieBreakShared},
+		{`{"title": "US Capitals", "tieBreaker": "lastClaim"}`, http.StatusOK, game.TieBreakLastClaim},
+		{`{"title": "US Capitals", "tieBreaker": "coinFlip"}`, http.StatusBadRequest, ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/create-game", strings.NewReader(c.body))
+		rec := httptest.NewRecorder()
+		gameinit.CreateHandler(globalState, rec, req)
+		if rec.Code != c.status {
+			t.Errorf("%s: status = %d, want %d", c.body, rec.Code, c.status)
+			continue
+		}
+		var resp gameinit.CreateResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if m := globalState.GetGame(resp.Code); m != nil && m.Options().TieBreaker != c.want {
+			t.Errorf("%s: TieBreaker = %q, want %q", c.body, m.Options().TieBreaker, c.want)
+		}
+	}
+}
+
+func TestCreateHandler_Teams(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = "../../../trivia"
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	cases := []struct {
+		body   string
+		status int
+	}{
+		{`{"title": "US Capitals", "teams": ["Red", "Blue"]}`, http.StatusOK},
+		{`{"title": "US Capitals", "teams": ["Red"]}`, http.StatusBadRequest},
+		{`{"title": "US Capitals", "teams": ["Red", "Red"]}`, http.StatusBadRequest},
+		{`{"title": "US Capitals", "teams": ["Red", " "]}`, http.StatusBadRequest},
+		{`{"title": "US Capitals", "teams": ["1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"]}`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/create-game", strings.NewReader(c.body))
+		rec := httptest.NewRecorder()
+		gameinit.CreateHandler(globalState, rec, req)
+		if rec.Code != c.status {
+			t.Errorf("%s: status = %d, want %d", c.body, rec.Code, c.status)
+		}
+	}
+}
+
+func TestCreateHandler_Mode(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = "../../../trivia"
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	cases := []struct {
+		body   string
+		status int
+		want   game.Mode
+	}{
+		{`{"title": "US Capitals"}`, http.StatusOK, game.ModeCompetitive},
+		{`{"title": "US Capitals", "mode": "coop"}`, http.StatusOK, game.ModeCoop},
+		{`{"title": "US Capitals", "mode": "solo"}`, http.StatusBadRequest, ""},
+		{`{"title": "US Capitals", "mode": "coop", "teams": ["Red", "Blue"]}`, http.StatusBadRequest, ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/create-game", strings.NewReader(c.body))
+		rec := httptest.NewRecorder()
+		gameinit.CreateHandler(globalState, rec, req)
+		if rec.Code != c.status {
+			t.Errorf("%s: status = %d, want %d", c.body, rec.Code, c.status)
+			continue
+		}
+		var resp gameinit.CreateResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if m := globalState.GetGame(resp.Code); m != nil && m.Options().Mode != c.want {
+			t.Errorf("%s: Mode = %q, want %q", c.body, m.Options().Mode, c.want)
+		}
+	}
+}
+
+func TestCreateHandler_Steal(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = "../../../trivia"
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	cases := []struct {
+		body                string
+		status              int
+		steal               bool
+		cooldown, maxSteals int
+	}{
+		{`{"title": "US Capitals"}`, http.StatusOK, false, game.DefaultStealCooldown, game.DefaultMaxSteals},
+		{`{"title": "US Capitals", "steal": {}}`, http.StatusOK, true, game.DefaultStealCooldown, game.DefaultMaxSteals},
+		{`{"title": "US Capitals", "steal": {"cooldown": 0, "maxSteals": 5}}`, http.StatusOK, true, 0, 5},
+		{`{"title": "US Capitals", "steal": {"cooldown": -1}}`, http.StatusBadRequest, false, 0, 0},
+		{`{"title": "US Capitals", "steal": {"maxSteals": 21}}`, http.StatusBadRequest, false, 0, 0},
+		{`{"title": "US Capitals", "mode": "coop", "steal": {}}`, http.StatusBadRequest, false, 0, 0},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/create-game", strings.NewReader(c.body))
+		rec := httptest.NewRecorder()
+		gameinit.CreateHandler(globalState, rec, req)
+		if rec.Code != c.status {
+			t.Errorf("%s: status = %d, want %d", c.body, rec.Code, c.status)
+			continue
+		}
+		var resp gameinit.CreateResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		m := globalState.GetGame(resp.Code)
+		if m == nil {
+			continue
+		}
+		if opts := m.Options(); opts.Steal != c.steal || opts.StealCooldown != c.cooldown || opts.MaxSteals != c.maxSteals {
+			t.Errorf("%s: steal %v, cooldown %d, max %d; want %v, %d, %d", c.body, opts.Steal, opts.StealCooldown, opts.MaxSteals, c.steal, c.cooldown, c.maxSteals)
+		}
+	}
+}
+
 // GetWSURLHandler returns a WS URL for the given code/username without validating
 // that the game exists or the username is free; that is checked in Connect().
 
@@ -322,3 +478,145 @@ func TestRegisterRoutes(t *testing.T) {
 		t.Errorf("RegisterRoutes /ws: status = %d, want 400", rec3.Code)
 	}
 }
+
+func TestCreateHandler_CustomQuiz(t *testing.T) {
+	globalState := state.NewGlobalState()
+	body := []byte(`{
+		"title": "Our Services",
+		"lobbyTime": 10,
+		"gameTime": 10,
+		"answers": ["billing", {"answer": "auth-service", "aliases": ["auth"]}, "search"]
+	}`)
+	req := httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	gameinit.CreateHandler(globalState, rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("CreateHandler custom quiz: status = %d, want 200 (%s)", rec.Code, rec.Body.String())
+	}
+	var resp gameinit.CreateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	m := globalState.GetGame(resp.Code)
+	if m == nil || m.Title != "Our Services" {
+		t.Fatalf("custom game not created from submitted answers: %+v", m)
+	}
+	if snap, _ := m.Snapshot(); len(snap.Board) != 3 {
+		t.Fatalf("custom game board = %v, want the 3 submitted answers", snap.Board)
+	}
+	if item := test.Guess(t, m, "Auth"); item != "auth-service" {
+		t.Errorf("guessing Auth claimed %q, want auth-service", item)
+	}
+}
+
+func TestCreateHandler_InvalidCustomQuiz(t *testing.T) {
+	globalState := state.NewGlobalState()
+	tooMany := make([]string, 101)
+	for i := range tooMany {
+		tooMany[i] = "item " + strings.Repeat("x", i%50) + string(rune('a'+i%26)) + string(rune('a'+i/26))
+	}
+	cases := map[string]any{
+		"empty":      []string{},
+		"too many":   tooMany,
+		"duplicate":  []string{"Billing", "billing"},
+		"blank":      []string{"billing", "   "},
+		"too long":   []string{strings.Repeat("a", 61)},
+		"conflict":   []any{"billing", map[string]any{"answer": "search", "aliases": []string{"Billing"}}},
+		"misspelled": []any{map[string]any{"answer": "search", "alias": []string{"find"}}},
+	}
+	for name, answers := range cases {
+		body, _ := json.Marshal(map[string]any{"title": "Ours", "lobbyTime": 10, "gameTime": 10, "answers": answers})
+		req := httptest.NewRequest(http.MethodPost, "/create-game", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		gameinit.CreateHandler(globalState, rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("CreateHandler custom quiz %s: status = %d, want %d", name, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestConnect_ReconnectWithToken(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = "../../../trivia"
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	m := globalState.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME)
+	if m == nil {
+		t.Fatal("Create failed")
+	}
+	code := m.Code
+
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, globalState)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?game=" + code + "&user=LeBron"
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("WebSocket dial: %v", err)
+	}
+	var joined map[string]string
+	if err := conn.ReadJSON(&joined); err != nil {
+		t.Fatalf("read json: %v", err)
+	}
+	token := joined["token"]
+	if joined["type"] != "success" || token == "" {
+		t.Fatalf("join: got %+v, want success with a token", joined)
+	}
+
+	// the player claims a square, then drops mid-game
+	if err := m.Command(game.CommandStart); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	m.Claim(game.PlayerRequest{Username: "LeBron", Code: code, Item: "Boise"})
+	color := m.Player("LeBron").Color
+	conn.Close()
+
+	for _, bad := range []string{"", "wrong"} {
+		c, _, err := websocket.DefaultDialer.Dial(wsURL+"&token="+bad, nil)
+		if err != nil {
+			t.Fatalf("WebSocket dial: %v", err)
+		}
+		var msg map[string]string
+		if err := c.ReadJSON(&msg); err != nil {
+			t.Fatalf("read json: %v", err)
+		}
+		if msg["type"] != "error" {
+			t.Errorf("reconnect with token %q: got %+v, want an error", bad, msg)
+		}
+		c.Close()
+	}
+
+	conn, _, err = websocket.DefaultDialer.Dial(wsURL+"&token="+token, nil)
+	if err != nil {
+		t.Fatalf("WebSocket dial: %v", err)
+	}
+	defer conn.Close()
+	var rejoined map[string]string
+	if err := conn.ReadJSON(&rejoined); err != nil {
+		t.Fatalf("read json: %v", err)
+	}
+	if rejoined["type"] != "success" || rejoined["token"] != token {
+		t.Fatalf("reconnect: got %+v, want success with the same token", rejoined)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var event game.GameEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("waiting for snapshot: %v", err)
+		}
+		if event.Type != "Snapshot" {
+			continue
+		}
+		if !event.Started {
+			t.Error("snapshot: Started = false, want true")
+		}
+		if p := event.State["Boise"]; p == nil || p.Username != "LeBron" || p.Color != color {
+			t.Errorf("snapshot: Boise = %+v, want LeBron's claim in %s", p, color)
+		}
+		break
+	}
+}
@@ -0,0 +1,132 @@
+package gameinit_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	clock "server/clock"
+	game "server/game"
+	gameinit "server/game-init"
+	"server/state"
+	"strings"
+	"testing"
+	"time"
+)
+
+// finishGame creates a game through the handler, has LeBron claim Boise and
+// ends it. The game closes and is removed on the next tick.
+func finishGame(t *testing.T, globalState *state.GlobalState, mux *http.ServeMux, clk *clock.Fake) gameinit.CreateResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/create-game", strings.NewReader(`{"title": "US Capitals", "lingerTime": 0}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	var resp gameinit.CreateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode create response: %v", err)
+	}
+	m := globalState.GetGame(resp.Code)
+	if _, _, err := m.Join("LeBron", ""); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	clk.WaitForTickers(1)
+	if err := m.Command(game.CommandStart); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	m.Claim(game.PlayerRequest{Username: "LeBron", Item: "Boise"})
+	if err := m.Command(game.CommandEnd); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	return resp
+}
+
+func get(t *testing.T, mux *http.ServeMux, url string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	return rec
+}
+
+func TestResultsHandler_OutlivesTheGame(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = "../../../trivia"
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	clk := clock.NewFake()
+	globalState.SetClock(clk)
+	globalState.SetResultsRetention(time.Hour)
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, globalState)
+
+	resp := finishGame(t, globalState, mux, clk)
+	if resp.ResultsURL != "http://example.com/games/"+resp.Code+"/results" {
+		t.Errorf("resultsUrl = %q", resp.ResultsURL)
+	}
+	path := "/games/" + resp.Code + "/results"
+
+	check := func(when string) {
+		t.Helper()
+		rec := get(t, mux, path)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", when, rec.Code)
+		}
+		var results game.Results
+		if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+			t.Fatalf("%s: decode: %v", when, err)
+		}
+		if results.Code != resp.Code || len(results.Standings) != 1 || !reflect.DeepEqual(results.Standings[0].Items, []string{"Boise"}) {
+			t.Errorf("%s: results = %+v, want LeBron with Boise", when, results)
+		}
+		if len(results.Unfound) == 0 {
+			t.Errorf("%s: no unfound squares", when)
+		}
+	}
+	check("while lingering")
+
+	clk.Advance(time.Second)
+	deadline := time.Now().Add(5 * time.Second)
+	for globalState.GetGame(resp.Code) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("game was never removed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	check("after the game was removed")
+
+	rec := get(t, mux, path+".csv")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("csv: status = %d, Content-Type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	want := [][]string{{"rank", "username", "correct", "items"}, {"1", "LeBron", "1", "Boise"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("csv = %v, want %v", rows, want)
+	}
+
+	clk.Advance(time.Hour)
+	if rec := get(t, mux, path); rec.Code != http.StatusNotFound {
+		t.Errorf("after retention: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestResultsHandler_NotFinished(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = "../../../trivia"
+	defer func() { state.TriviaBasePath = saved }()
+
+	globalState := state.NewGlobalState()
+	m := globalState.Create("US Capitals", 10, 10)
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, globalState)
+
+	for _, code := range []string{m.Code, "ZZZZZZ"} {
+		if rec := get(t, mux, "/games/"+code+"/results"); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", code, rec.Code)
+		}
+	}
+}
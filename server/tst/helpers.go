@@ -0,0 +1,32 @@
+package tst
+
+import (
+	"testing"
+
+	game "server/game"
+)
+
+// Guess starts m if it hasn't started, submits guess as a player named
+// "tester" and returns the board item it claimed, or "" if it claimed none.
+func Guess(t *testing.T, m *game.Manager, guess string) string {
+	t.Helper()
+	if !m.HasPlayer("tester") {
+		if _, _, err := m.Join("tester", ""); err != nil {
+			t.Fatalf("join tester: %v", err)
+		}
+	}
+	if snap, _ := m.Snapshot(); !snap.Started {
+		if err := m.Command(game.CommandStart); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+	}
+	before, _ := m.Snapshot()
+	m.Claim(game.PlayerRequest{Username: "tester", Item: guess})
+	after, _ := m.Snapshot() // queued behind the claim, so it sees the result
+	for item, owner := range after.Board {
+		if owner == "tester" && before.Board[item] == "" {
+			return item
+		}
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package history_test
+
+import (
+	"errors"
+	"reflect"
+	clock "server/clock"
+	game "server/game"
+	history "server/history"
+	state "server/state"
+	test "server/tst"
+	"testing"
+	"time"
+)
+
+const testTriviaPath = "../../../trivia"
+
+// waitForMatches polls store until it has n matches.
+func waitForMatches(t *testing.T, store history.Store, n int) []*history.Match {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		matches, err := store.List()
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(matches) == n {
+			return matches
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("store has %d matches, want %d", len(matches), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// playRecorded plays a game on a fake clock that records to a temporary
+// store: LeBron claims Boise two seconds in, Steph Salem three seconds later,
+// and the host ends it. It returns the recorded match.
+func playRecorded(t *testing.T) (*state.GlobalState, *clock.Fake, *game.Manager, *history.Match) {
+	t.Helper()
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = testTriviaPath
+	defer func() { state.TriviaBasePath = saved }()
+
+	store, err := history.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	globalState := state.NewGlobalState()
+	clk := clock.NewFake()
+	globalState.SetClock(clk)
+	globalState.SetHistory(store)
+	m := globalState.CreateGame(state.GameOptions{Title: "US Capitals", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME, TieBreaker: game.TieBreakLastClaim})
+	for _, name := range []string{"LeBron", "Steph"} {
+		if _, _, err := m.Join(name, ""); err != nil {
+			t.Fatalf("join %s: %v", name, err)
+		}
+	}
+	go m.Run()
+	clk.WaitForTickers(1)
+	clk.Advance(test.LOBBY_TIME * time.Second)
+
+	clk.Advance(2 * time.Second)
+	m.Claim(game.PlayerRequest{Username: "LeBron", Item: "Boise"})
+	m.Snapshot()
+	clk.Advance(3 * time.Second)
+	m.Claim(game.PlayerRequest{Username: "Steph", Item: "Salem"})
+	if err := m.Command(game.CommandEnd); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+	return globalState, clk, m, waitForMatches(t, store, 1)[0]
+}
+
+func TestHistory_FinishedGameIsRecorded(t *testing.T) {
+	globalState, _, m, match := playRecorded(t)
+	store := globalState.History()
+	if match.Title != "US Capitals" || match.Code != m.Code {
+		t.Errorf("match is %s (%s), want US Capitals (%s)", match.Title, match.Code, m.Code)
+	}
+	settings := game.Settings{LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME, Strictness: game.StrictnessNormal, TieBreaker: game.TieBreakLastClaim, Mode: game.ModeCompetitive}
+	if match.Settings != settings {
+		t.Errorf("settings = %+v, want %+v", match.Settings, settings)
+	}
+	var claims []game.LogEntry
+	for _, e := range match.Events {
+		if e.Type == game.LogClaim {
+			claims = append(claims, e)
+		}
+	}
+	want := []game.LogEntry{
+		{Type: game.LogClaim, Elapsed: 2, Player: "LeBron", Item: "Boise"},
+		{Type: game.LogClaim, Elapsed: 5, Player: "Steph", Item: "Salem"},
+	}
+	if !reflect.DeepEqual(claims, want) {
+		t.Errorf("claims = %+v, want %+v", claims, want)
+	}
+	if len(match.Standings) != 2 || match.Standings[0].Username != "LeBron" || match.Standings[1].Rank != 2 {
+		t.Errorf("standings = %+v, want LeBron first and Steph second", match.Standings)
+	}
+
+	got, err := store.Get(match.ID)
+	if err != nil || !reflect.DeepEqual(got, match) {
+		t.Errorf("Get(%s) = %+v, %v; want the listed match", match.ID, got, err)
+	}
+}
+
+func TestFileStore_GetMissing(t *testing.T) {
+	store, err := history.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	for _, id := range []string{"abc123-1", "../escape", ""} {
+		if _, err := store.Get(id); !errors.Is(err, history.ErrNotFound) {
+			t.Errorf("Get(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestHistory_ResultsOutliveRetention(t *testing.T) {
+	globalState, _, m, match := playRecorded(t)
+	globalState.SetResultsRetention(0)
+	globalState.RemoveGame(m.Code)
+
+	results, ok := globalState.GetResults(m.Code)
+	if !ok || !reflect.DeepEqual(results, match.Results) {
+		t.Errorf("GetResults(%s) = %+v, %v; want the recorded match", m.Code, results, ok)
+	}
+	if _, ok := globalState.GetResults("ZZZZZZ"); ok {
+		t.Error("GetResults of an unknown code: ok = true")
+	}
+}
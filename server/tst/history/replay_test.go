@@ -0,0 +1,119 @@
+package history_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	gameinit "server/game-init"
+	protocol "server/protocol"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func dialReplay(t *testing.T, serverURL, path string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(serverURL, "http")+path, nil)
+	if err != nil {
+		t.Fatalf("WebSocket dial %s: %v", path, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readTypes reads n messages and returns their types, or every message
+// until the connection closes if n is 0. Claimed and Leaderboard messages
+// are decoded into the given pointers.
+func readTypes(t *testing.T, conn *websocket.Conn, n int, claimed *[]protocol.Claimed, board *protocol.Leaderboard) []protocol.MessageType {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var types []protocol.MessageType
+	for n == 0 || len(types) < n {
+		var msg map[string]any
+		if err := conn.ReadJSON(&msg); err != nil {
+			if _, closed := err.(*websocket.CloseError); closed && n == 0 {
+				return types
+			}
+			t.Fatalf("read: %v", err)
+		}
+		msgType := protocol.MessageType(msg["type"].(string))
+		types = append(types, msgType)
+		switch msgType {
+		case protocol.TypeClaimed:
+			*claimed = append(*claimed, protocol.Claimed{Type: msgType, Seq: int(msg["seq"].(float64)), Item: msg["item"].(string), Player: msg["player"].(string)})
+		case protocol.TypeLeaderboard:
+			for _, e := range msg["entries"].([]any) {
+				board.Entries = append(board.Entries, protocol.LeaderboardEntry{Username: e.(map[string]any)["username"].(string)})
+			}
+		}
+	}
+	return types
+}
+
+func TestReplay_StreamsTheMatchAsLiveEvents(t *testing.T) {
+	globalState, clk, _, match := playRecorded(t)
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, globalState)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	// the game's log spans 15s: joins 10s before the start, claims at 2s and 5s
+	conn := dialReplay(t, server.URL, "/replay/"+match.ID+"?v=2&speed=2")
+	var claimed []protocol.Claimed
+	var board protocol.Leaderboard
+	got := readTypes(t, conn, 2, &claimed, &board)
+	clk.WaitForTickers(2) // the lingering game's and the replay's
+
+	clk.Advance(4 * time.Second) // 8s into the match
+	got = append(got, readTypes(t, conn, 3, &claimed, &board)...)
+	want := []protocol.MessageType{protocol.TypeWelcome, protocol.TypeSnapshot, protocol.TypePlayers, protocol.TypePlayers, protocol.TypePhase}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("first 8s = %v, want %v", got, want)
+	}
+
+	clk.Advance(4 * time.Second)
+	got = readTypes(t, conn, 0, &claimed, &board)
+	want = []protocol.MessageType{protocol.TypePhase, protocol.TypeStart, protocol.TypeClaimed, protocol.TypeClaimed, protocol.TypePhase, protocol.TypeLeaderboard}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rest = %v, want %v", got, want)
+	}
+	wantClaims := []protocol.Claimed{
+		{Type: protocol.TypeClaimed, Seq: 1, Item: "Boise", Player: "LeBron"},
+		{Type: protocol.TypeClaimed, Seq: 2, Item: "Salem", Player: "Steph"},
+	}
+	if !reflect.DeepEqual(claimed, wantClaims) {
+		t.Errorf("claims = %+v, want %+v", claimed, wantClaims)
+	}
+	if len(board.Entries) != 2 || board.Entries[0].Username != "LeBron" {
+		t.Errorf("leaderboard = %+v, want LeBron then Steph", board.Entries)
+	}
+}
+
+func TestReplay_Rejects(t *testing.T) {
+	globalState, _, _, match := playRecorded(t)
+	mux := http.NewServeMux()
+	gameinit.RegisterRoutes(mux, globalState)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	cases := []struct {
+		path string
+		want protocol.ErrorCode
+	}{
+		{"/replay/nosuchmatch-1?v=2", protocol.ErrMatchNotFound},
+		{"/replay/" + match.ID + "?v=2&speed=100", protocol.ErrInvalidParams},
+		{"/replay/" + match.ID + "?v=2&speed=fast", protocol.ErrInvalidParams},
+		{"/replay/" + match.ID + "?v=0", protocol.ErrUnsupportedVersion},
+	}
+	for _, c := range cases {
+		var got protocol.Error
+		if err := dialReplay(t, server.URL, c.path).ReadJSON(&got); err != nil {
+			t.Fatalf("%s: %v", c.path, err)
+		}
+		if got.Code != c.want {
+			t.Errorf("%s: code = %q, want %q", c.path, got.Code, c.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package jsonstore_test
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	jsonstore "server/jsonstore"
+	"testing"
+)
+
+var errMissing = errors.New("missing")
+
+type note struct {
+	Text string `json:"text"`
+}
+
+func TestDir_PutGetListDelete(t *testing.T) {
+	dir := t.TempDir()
+	d, err := jsonstore.New[note](dir, regexp.MustCompile(`^[a-z]+$`), errMissing)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := d.Put(id, &note{Text: id}); err != nil {
+			t.Fatalf("Put(%s): %v", id, err)
+		}
+	}
+	if got, err := d.Get("a"); err != nil || got.Text != "a" {
+		t.Errorf("Get(a) = %+v, %v; want a", got, err)
+	}
+	if notes, err := d.List(); err != nil || len(notes) != 2 {
+		t.Errorf("List = %d notes, %v; want 2", len(notes), err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("directory has %d files, want 2 and no temporary files", len(entries))
+	}
+
+	if err := d.Delete("a"); err != nil {
+		t.Fatalf("Delete(a): %v", err)
+	}
+	for _, id := range []string{"a", "../b", ""} {
+		if _, err := d.Get(id); !errors.Is(err, errMissing) {
+			t.Errorf("Get(%q) error = %v, want the not found error", id, err)
+		}
+	}
+	if err := d.Delete("a"); !errors.Is(err, errMissing) {
+		t.Errorf("second Delete(a) error = %v, want the not found error", err)
+	}
+	if err := d.Put("../b", &note{}); !errors.Is(err, errMissing) {
+		t.Errorf("Put(../b) error = %v, want the not found error", err)
+	}
+}
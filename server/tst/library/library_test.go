@@ -0,0 +1,268 @@
+package library_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	library "server/library"
+	state "server/state"
+	trivia "server/trivia"
+	test "server/tst"
+)
+
+const testTriviaPath = "../../../trivia"
+
+// setupLibrary returns a mux serving the library over a temporary store, along
+// with the game state sharing its catalog.
+func setupLibrary(t *testing.T) (*http.ServeMux, *state.GlobalState, string) {
+	t.Helper()
+	catalog, err := trivia.OpenLiveCatalog(testTriviaPath)
+	if err != nil {
+		t.Fatalf("OpenLiveCatalog: %v", err)
+	}
+	dir := t.TempDir()
+	store, err := library.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	lib, err := library.New(store, catalog)
+	if err != nil {
+		t.Fatalf("library.New: %v", err)
+	}
+	mux := http.NewServeMux()
+	library.RegisterRoutes(mux, lib)
+	trivia.RegisterRoutes(mux, catalog)
+	return mux, state.NewGlobalStateWithCatalog(catalog), dir
+}
+
+func do(t *testing.T, mux *http.ServeMux, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func servicesQuiz(published bool) map[string]any {
+	return map[string]any{
+		"title":     "Our Services",
+		"tags":      []string{"work"},
+		"answers":   []any{"billing", map[string]any{"answer": "auth-service", "aliases": []string{"auth"}, "hint": "logins"}},
+		"published": published,
+	}
+}
+
+func createQuiz(t *testing.T, mux *http.ServeMux, token string, body any) library.QuizResponse {
+	t.Helper()
+	rec := do(t, mux, http.MethodPost, "/library/quizzes", token, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want 201 (%s)", rec.Code, rec.Body.String())
+	}
+	var resp library.QuizResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return resp
+}
+
+func TestLibrary_DraftIsPrivateAndNotPlayable(t *testing.T) {
+	mux, gs, _ := setupLibrary(t)
+	created := createQuiz(t, mux, "", servicesQuiz(false))
+	if created.OwnerToken == "" || created.ID == "" || !created.Owned {
+		t.Fatalf("create response = %+v, want an id and owner token", created)
+	}
+	if len(created.Quiz.Answers) != 2 {
+		t.Errorf("owner should see the answers, got %+v", created.Quiz)
+	}
+
+	if rec := do(t, mux, http.MethodGet, "/library/quizzes/"+created.ID, "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("draft for a stranger: status = %d, want 404", rec.Code)
+	}
+	if rec := do(t, mux, http.MethodGet, "/library/quizzes/"+created.ID, created.OwnerToken, nil); rec.Code != http.StatusOK {
+		t.Errorf("draft for its owner: status = %d, want 200", rec.Code)
+	}
+
+	var infos []library.QuizInfo
+	json.NewDecoder(do(t, mux, http.MethodGet, "/library/quizzes", "", nil).Body).Decode(&infos)
+	if len(infos) != 0 {
+		t.Errorf("strangers should not see drafts in the list, got %+v", infos)
+	}
+	json.NewDecoder(do(t, mux, http.MethodGet, "/library/quizzes", created.OwnerToken, nil).Body).Decode(&infos)
+	if len(infos) != 1 || !infos[0].Owned {
+		t.Errorf("owner should see their draft in the list, got %+v", infos)
+	}
+
+	if gs.Create("Our Services", test.LOBBY_TIME, test.GAME_TIME) != nil {
+		t.Error("drafts should not be playable")
+	}
+}
+
+func TestLibrary_PublishedQuizIsPlayable(t *testing.T) {
+	mux, gs, _ := setupLibrary(t)
+	created := createQuiz(t, mux, "my-token", servicesQuiz(true))
+	if created.OwnerToken != "my-token" {
+		t.Errorf("OwnerToken = %q, want the token the caller presented", created.OwnerToken)
+	}
+
+	m := gs.Create("Our Services", test.LOBBY_TIME, test.GAME_TIME)
+	if m == nil {
+		t.Fatal("published library quiz should be playable like a bundled one")
+	}
+	if snap, _ := m.Snapshot(); len(snap.Board) != 2 {
+		t.Fatalf("board = %v, want 2 squares", snap.Board)
+	}
+	if item := test.Guess(t, m, "auth"); item != "auth-service" {
+		t.Errorf("guessing auth claimed %q, want auth-service", item)
+	}
+
+	var keys []string
+	json.NewDecoder(do(t, mux, http.MethodGet, "/trivia/keys?file=library", "", nil).Body).Decode(&keys)
+	if len(keys) != 1 || keys[0] != "Our Services" {
+		t.Errorf("/trivia/keys?file=library = %v", keys)
+	}
+
+	rec := do(t, mux, http.MethodGet, "/library/quizzes/"+created.ID, "", nil)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "billing") {
+		t.Errorf("published quiz for a stranger: status = %d, body = %s; want 200 without answers", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLibrary_OnlyOwnerCanEditOrDelete(t *testing.T) {
+	mux, gs, _ := setupLibrary(t)
+	created := createQuiz(t, mux, "owner", servicesQuiz(true))
+	path := "/library/quizzes/" + created.ID
+
+	edited := servicesQuiz(true)
+	edited["answers"] = []string{"billing", "search", "payments"}
+	if rec := do(t, mux, http.MethodPut, path, "intruder", edited); rec.Code != http.StatusForbidden {
+		t.Errorf("update by stranger: status = %d, want 403", rec.Code)
+	}
+	if rec := do(t, mux, http.MethodDelete, path, "", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("delete without token: status = %d, want 403", rec.Code)
+	}
+
+	if rec := do(t, mux, http.MethodPut, path, "owner", edited); rec.Code != http.StatusOK {
+		t.Fatalf("update by owner: status = %d (%s)", rec.Code, rec.Body.String())
+	}
+	m := gs.Create("Our Services", test.LOBBY_TIME, test.GAME_TIME)
+	if m == nil {
+		t.Fatal("edited quiz should still be playable")
+	}
+	if snap, _ := m.Snapshot(); len(snap.Board) != 3 {
+		t.Error("new games should use the edited quiz")
+	}
+
+	if rec := do(t, mux, http.MethodDelete, path, "owner", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("delete by owner: status = %d, want 204", rec.Code)
+	}
+	if rec := do(t, mux, http.MethodGet, path, "owner", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("get after delete: status = %d, want 404", rec.Code)
+	}
+	if gs.Create("Our Services", test.LOBBY_TIME, test.GAME_TIME) != nil {
+		t.Error("deleted quizzes should no longer be playable")
+	}
+}
+
+func TestLibrary_RejectsInvalidAndConflictingQuizzes(t *testing.T) {
+	mux, _, _ := setupLibrary(t)
+	bundled := servicesQuiz(true)
+	bundled["title"] = "US Capitals"
+	if rec := do(t, mux, http.MethodPost, "/library/quizzes", "", bundled); rec.Code != http.StatusConflict {
+		t.Errorf("bundled title: status = %d, want 409", rec.Code)
+	}
+
+	createQuiz(t, mux, "a", servicesQuiz(false))
+	if rec := do(t, mux, http.MethodPost, "/library/quizzes", "b", servicesQuiz(true)); rec.Code != http.StatusConflict {
+		t.Errorf("title of another library quiz: status = %d, want 409", rec.Code)
+	}
+
+	invalid := servicesQuiz(true)
+	invalid["title"] = "Dupes"
+	invalid["answers"] = []string{"billing", "Billing"}
+	if rec := do(t, mux, http.MethodPost, "/library/quizzes", "", invalid); rec.Code != http.StatusBadRequest {
+		t.Errorf("duplicate answers: status = %d, want 400", rec.Code)
+	}
+	invalid["answers"] = []string{"billing"}
+	invalid["difficulty"] = "impossible"
+	if rec := do(t, mux, http.MethodPost, "/library/quizzes", "", invalid); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad difficulty: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestFileStore_PersistsAcrossRestarts(t *testing.T) {
+	mux, _, dir := setupLibrary(t)
+	createQuiz(t, mux, "owner", servicesQuiz(true))
+
+	catalog, err := trivia.OpenLiveCatalog(testTriviaPath)
+	if err != nil {
+		t.Fatalf("OpenLiveCatalog: %v", err)
+	}
+	store, err := library.NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	if _, err := library.New(store, catalog); err != nil {
+		t.Fatalf("library.New: %v", err)
+	}
+	if catalog.Current().Quiz("Our Services") == nil {
+		t.Error("published quizzes should be loaded from the store on startup")
+	}
+	if _, err := store.Get("../../etc"); err != library.ErrNotFound {
+		t.Errorf("Get with a path in the id = %v, want ErrNotFound", err)
+	}
+}
+
+// listFailsAfterPut is a store whose List fails right after a write, as if
+// the disk went away between saving a quiz and publishing it.
+type listFailsAfterPut struct {
+	*library.FileStore
+	failNext bool
+}
+
+func (s *listFailsAfterPut) Put(q *library.Quiz) error {
+	s.failNext = true
+	return s.FileStore.Put(q)
+}
+
+func (s *listFailsAfterPut) List() ([]*library.Quiz, error) {
+	if s.failNext {
+		s.failNext = false
+		return nil, errors.New("disk unavailable")
+	}
+	return s.FileStore.List()
+}
+
+func TestLibrary_SavedQuizSurvivesFailedPublish(t *testing.T) {
+	fs, err := library.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	store := &listFailsAfterPut{FileStore: fs}
+	lib, err := library.New(store, trivia.StaticCatalog(trivia.NewCatalog()))
+	if err != nil {
+		t.Fatalf("library.New: %v", err)
+	}
+	quiz := trivia.Quiz{Title: "Our Services", Answers: []trivia.Answer{{Answer: "billing"}, {Answer: "search"}}}
+	q, token, err := lib.Create("", quiz, true)
+	if err != nil || token == "" {
+		t.Fatalf("Create = %v, %q; want the saved quiz and its owner token", err, token)
+	}
+	if _, err := lib.Get(q.ID); err != nil {
+		t.Errorf("Get after create: %v", err)
+	}
+	if _, err := lib.Update(q.ID, token, quiz, false); err != nil {
+		t.Errorf("Update: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package protocol_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	protocol "server/protocol"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]int{
+		"":  protocol.LegacyVersion,
+		"1": 1,
+		"2": 2,
+		"9": protocol.Version, // newer clients fall back to what the server speaks
+	}
+	for requested, want := range cases {
+		if got, err := protocol.Negotiate(requested); err != nil || got != want {
+			t.Errorf("Negotiate(%q) = %d, %v; want %d", requested, got, err, want)
+		}
+	}
+	for _, bad := range []string{"0", "-1", "two"} {
+		if _, err := protocol.Negotiate(bad); err == nil {
+			t.Errorf("Negotiate(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSchema_DescribesEveryMessage(t *testing.T) {
+	data, err := protocol.Schema()
+	if err != nil {
+		t.Fatalf("Schema: %v", err)
+	}
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]struct {
+				Const string `json:"const"`
+			} `json:"properties"`
+			OneOf []struct {
+				Ref string `json:"$ref"`
+			} `json:"oneOf"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("decode schema: %v", err)
+	}
+	check := func(union string, messages map[protocol.MessageType]any) {
+		if n := len(schema.Defs[union].OneOf); n != len(messages) {
+			t.Errorf("%s has %d variants, want %d", union, n, len(messages))
+		}
+		for _, ref := range schema.Defs[union].OneOf {
+			name := ref.Ref[len("#/$defs/"):]
+			msgType := protocol.MessageType(schema.Defs[name].Properties["type"].Const)
+			if _, ok := messages[msgType]; !ok {
+				t.Errorf("%s: %s has type %q, which is not a %s", union, name, msgType, union)
+			}
+		}
+	}
+	check("ServerMessage", protocol.ServerMessages)
+	check("ClientMessage", protocol.ClientMessages)
+}
+
+func TestSchema_CommittedFileIsCurrent(t *testing.T) {
+	committed, err := os.ReadFile("../../protocol/schema.json")
+	if err != nil {
+		t.Fatalf("read schema.json: %v", err)
+	}
+	data, _ := protocol.Schema()
+	if !bytes.Equal(bytes.TrimSpace(committed), data) {
+		t.Error("protocol/schema.json is stale; run make protocol-schema")
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	game "server/game"
 	state "server/state"
+	trivia "server/trivia"
 	test "server/tst"
 )
 
@@ -24,13 +25,13 @@ func TestSetGameAndGetGame(t *testing.T) {
 	if g := s.GetGame(code); g != nil {
 		t.Errorf("GetGame(%q) expected nil, got %v", code, g)
 	}
-	m := game.NewManager("US Capitals", code, test.LOBBY_TIME, test.GAME_TIME)
+	m := game.NewManager("US Capitals", code, test.LOBBY_TIME, test.GAME_TIME, nil, game.DefaultOptions())
 	s.SetGame(code, m)
 	if g := s.GetGame(code); g != m {
 		t.Errorf("GetGame(%q) expected same manager, got %v", code, g)
 	}
 	// Overwrite
-	m2 := game.NewManager("NBA Teams", code, test.LOBBY_TIME, test.GAME_TIME)
+	m2 := game.NewManager("NBA Teams", code, test.LOBBY_TIME, test.GAME_TIME, nil, game.DefaultOptions())
 	s.SetGame(code, m2)
 	if g := s.GetGame(code); g != m2 {
 		t.Errorf("GetGame after SetGame expected m2, got %v", g)
@@ -55,7 +56,7 @@ func TestCreate(t *testing.T) {
 	if m.Code == "" || len(m.Code) != 6 {
 		t.Errorf("Create Manager Code should be 6 chars, got %q", m.Code)
 	}
-	if len(m.Board) == 0 {
+	if snap, _ := m.Snapshot(); len(snap.Board) == 0 {
 		t.Error("Create should populate Board from trivia")
 	}
 
@@ -103,3 +104,66 @@ func TestCanJoin(t *testing.T) {
 		t.Error("CanJoin with invalid code expected false, false")
 	}
 }
+
+func TestCreate_LoadsAliasesFromTrivia(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = "../../../trivia"
+	defer func() { state.TriviaBasePath = saved }()
+
+	s := state.NewGlobalState()
+	m := s.CreateGame(state.GameOptions{Title: "US Capitals", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+	if m == nil {
+		t.Fatal("CreateGame failed")
+	}
+	snap, _ := m.Snapshot()
+	if _, ok := snap.Board["Saint Paul"]; !ok {
+		t.Fatal("Board should contain canonical item Saint Paul")
+	}
+	if item := test.Guess(t, m, "St. Paul"); item != "Saint Paul" {
+		t.Errorf("guessing St. Paul claimed %q, want Saint Paul", item)
+	}
+}
+
+func TestCreate_UsesQuizDefaults(t *testing.T) {
+	saved := state.TriviaBasePath
+	state.TriviaBasePath = "../../../trivia"
+	defer func() { state.TriviaBasePath = saved }()
+
+	s := state.NewGlobalState()
+	// NFL Teams sets a default gameTime but no lobbyTime
+	m := s.CreateGame(state.GameOptions{Title: "NFL Teams"})
+	if m == nil {
+		t.Fatal("CreateGame failed")
+	}
+	if m.GameTime != 180 {
+		t.Errorf("GameTime = %d, want quiz default 180", m.GameTime)
+	}
+	if m.LobbyTime != state.DefaultLobbyTime {
+		t.Errorf("LobbyTime = %d, want server default %d", m.LobbyTime, state.DefaultLobbyTime)
+	}
+
+	m2 := s.CreateGame(state.GameOptions{Title: "NFL Teams", LobbyTime: test.LOBBY_TIME, GameTime: test.GAME_TIME})
+	if m2 == nil || m2.LobbyTime != test.LOBBY_TIME || m2.GameTime != test.GAME_TIME {
+		t.Error("explicit times should override quiz defaults")
+	}
+}
+
+func TestCreate_UsesSharedCatalog(t *testing.T) {
+	catalog := trivia.NewCatalog()
+	catalog.Add("custom.json", []trivia.Quiz{{Title: "Colors", Answers: []trivia.Answer{{Answer: "Red"}, {Answer: "Blue"}}}})
+
+	s := state.NewGlobalStateWithCatalog(trivia.StaticCatalog(catalog))
+	if s.Catalog() != catalog {
+		t.Error("Catalog() should return the catalog passed in")
+	}
+	m := s.Create("Colors", test.LOBBY_TIME, test.GAME_TIME)
+	if m == nil {
+		t.Fatal("Create should build the board from the shared catalog")
+	}
+	if snap, _ := m.Snapshot(); len(snap.Board) != 2 {
+		t.Fatalf("board = %v, want the catalog's 2 answers", snap.Board)
+	}
+	if s.Create("US Capitals", test.LOBBY_TIME, test.GAME_TIME) != nil {
+		t.Error("Create should not read quizzes that are not in the catalog")
+	}
+}
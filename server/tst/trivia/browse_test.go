@@ -0,0 +1,122 @@
+package trivia_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	trivia "server/trivia"
+)
+
+// browseMux serves the trivia routes over the bundled trivia directory.
+func browseMux(t *testing.T) *http.ServeMux {
+	t.Helper()
+	c, err := trivia.LoadCatalog(testTriviaPath)
+	if err != nil {
+		t.Fatalf("LoadCatalog: %v", err)
+	}
+	mux := http.NewServeMux()
+	trivia.RegisterRoutes(mux, trivia.StaticCatalog(c))
+	return mux
+}
+
+func listQuizzes(t *testing.T, mux *http.ServeMux, query string) (int, trivia.QuizListResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/quizzes?"+query, nil))
+	var resp trivia.QuizListResponse
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func titlesOf(resp trivia.QuizListResponse) string {
+	titles := make([]string, 0, len(resp.Quizzes))
+	for _, q := range resp.Quizzes {
+		titles = append(titles, q.Title)
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestListQuizzes_All(t *testing.T) {
+	code, resp := listQuizzes(t, browseMux(t), "")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if resp.Total != 4 || titlesOf(resp) != "European Countries,NBA Teams,NFL Teams,US Capitals" {
+		t.Errorf("quizzes = %s (total %d)", titlesOf(resp), resp.Total)
+	}
+	for _, q := range resp.Quizzes {
+		if q.Title == "NFL Teams" && (q.Category != "sports" || q.AnswerCount != 32 || q.Difficulty != "easy") {
+			t.Errorf("NFL Teams summary = %+v", q)
+		}
+	}
+}
+
+func TestListQuizzes_FiltersAndSorting(t *testing.T) {
+	mux := browseMux(t)
+	cases := map[string]string{
+		"q=capital":                    "US Capitals",
+		"q=FOOTBALL":                   "NFL Teams",
+		"tag=sports&tag=basketball":    "NBA Teams",
+		"category=geography":           "European Countries,US Capitals",
+		"difficulty=easy":              "NBA Teams,NFL Teams",
+		"sort=-answers":                "European Countries,US Capitals,NFL Teams,NBA Teams",
+		"sort=category&pageSize=2":     "European Countries,US Capitals",
+		"sort=title&page=2&pageSize=3": "US Capitals",
+		"page=9":                       "",
+	}
+	for query, want := range cases {
+		code, resp := listQuizzes(t, mux, query)
+		if code != http.StatusOK {
+			t.Errorf("%s: status = %d", query, code)
+			continue
+		}
+		if got := titlesOf(resp); got != want {
+			t.Errorf("%s: quizzes = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestListQuizzes_BadParams(t *testing.T) {
+	mux := browseMux(t)
+	for _, query := range []string{"sort=size", "page=0", "pageSize=abc", "page=100000000000000000&pageSize=100"} {
+		if code, _ := listQuizzes(t, mux, query); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, code)
+		}
+	}
+}
+
+func TestGetQuiz_DoesNotLeakAnswers(t *testing.T) {
+	mux := browseMux(t)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/quizzes/"+url.PathEscape("NFL Teams"), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, secret := range []string{"Bears", "Niners", "Chicago"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("quiz detail leaks %q: %s", secret, body)
+		}
+	}
+	var detail trivia.QuizDetail
+	if err := json.Unmarshal([]byte(body), &detail); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if detail.AnswerCount != 32 || len(detail.Groups) != 8 {
+		t.Errorf("detail = %+v, want 32 answers in 8 divisions", detail)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/quizzes/Nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown quiz: status = %d, want 404", rec.Code)
+	}
+}
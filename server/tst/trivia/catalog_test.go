@@ -0,0 +1,121 @@
+package trivia_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	trivia "server/trivia"
+)
+
+// writeTriviaDir creates a temporary trivia directory holding files.
+func writeTriviaDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestLoadCatalog_IndexesBundledTrivia(t *testing.T) {
+	c, err := trivia.LoadCatalog(testTriviaPath)
+	if err != nil {
+		t.Fatalf("LoadCatalog: %v", err)
+	}
+	if len(c.Rejected) != 0 {
+		t.Errorf("bundled trivia rejected: %v", c.Rejected)
+	}
+	if q := c.Quiz("US Capitals"); q == nil || len(q.Answers) != 50 {
+		t.Error("expected US Capitals with 50 answers")
+	}
+	if e := c.Entry("NFL Teams"); e == nil || e.File != "sports.json" {
+		t.Error("expected NFL Teams to come from sports.json")
+	}
+	if !slices.Equal(c.Files(), []string{"geography.json", "sports.json"}) {
+		t.Errorf("Files = %v", c.Files())
+	}
+	if len(c.Tagged("SPORTS")) != 2 {
+		t.Errorf("Tagged(SPORTS) = %d quizzes, want 2", len(c.Tagged("SPORTS")))
+	}
+}
+
+func TestLoadCatalog_DuplicatesAndRejects(t *testing.T) {
+	dir := writeTriviaDir(t, map[string]string{
+		"a.json":      `{"Shared": ["one"], "Only A": ["two"]}`,
+		"b.json":      `{"version": 2, "quizzes": [{"title": "Shared", "tags": ["Misc"], "answers": ["three"]}]}`,
+		"broken.json": `{"version": 2,`,
+		"notes.txt":   `ignored`,
+	})
+	c, err := trivia.LoadCatalog(dir)
+	if err != nil {
+		t.Fatalf("LoadCatalog: %v", err)
+	}
+	if _, ok := c.Rejected["broken.json"]; !ok || len(c.Rejected) != 1 {
+		t.Errorf("Rejected = %v, want only broken.json", c.Rejected)
+	}
+	if len(c.Duplicates) != 1 || c.Duplicates[0].Title != "Shared" || c.Duplicates[0].Kept != "a.json" {
+		t.Fatalf("Duplicates = %v, want Shared kept from a.json", c.Duplicates)
+	}
+	if q := c.Quiz("Shared"); q == nil || q.Answers[0].Answer != "one" {
+		t.Error("first definition of Shared should win")
+	}
+	if titles, ok := c.Titles("b"); !ok || len(titles) != 0 {
+		t.Errorf("Titles(b) = %v, %v; want empty list for a file whose only quiz was a duplicate", titles, ok)
+	}
+	if _, ok := c.Titles("missing.json"); ok {
+		t.Error("Titles(missing.json) should not be found")
+	}
+}
+
+func TestLoadCatalog_MissingDir(t *testing.T) {
+	c, err := trivia.LoadCatalog(filepath.Join(t.TempDir(), "nope"))
+	if err == nil {
+		t.Error("LoadCatalog on a missing directory expected error")
+	}
+	if c == nil || len(c.Entries()) != 0 {
+		t.Error("LoadCatalog on a missing directory should still return an empty catalog")
+	}
+}
+
+func TestRoutes_ServeFromCatalog(t *testing.T) {
+	c, err := trivia.LoadCatalog(testTriviaPath)
+	if err != nil {
+		t.Fatalf("LoadCatalog: %v", err)
+	}
+	mux := http.NewServeMux()
+	trivia.RegisterRoutes(mux, trivia.StaticCatalog(c))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/files", nil))
+	var files []string
+	if err := json.NewDecoder(rec.Body).Decode(&files); err != nil {
+		t.Fatalf("decode files: %v", err)
+	}
+	if !slices.Equal(files, []string{"geography.json", "sports.json"}) {
+		t.Errorf("/trivia/files = %v", files)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/keys?file=sports", nil))
+	var keys []string
+	if err := json.NewDecoder(rec.Body).Decode(&keys); err != nil {
+		t.Fatalf("decode keys: %v", err)
+	}
+	if !slices.Equal(keys, []string{"NBA Teams", "NFL Teams"}) {
+		t.Errorf("/trivia/keys?file=sports = %v", keys)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trivia/keys?file=../../etc/passwd", nil))
+	keys = nil
+	if err := json.NewDecoder(rec.Body).Decode(&keys); err != nil || len(keys) != 0 {
+		t.Errorf("/trivia/keys with a path outside the catalog = %v, want []", keys)
+	}
+}
@@ -0,0 +1,85 @@
+package trivia_test
+
+import (
+	"strings"
+	"testing"
+
+	trivia "server/trivia"
+)
+
+func TestLint_BundledTriviaIsClean(t *testing.T) {
+	problems, err := trivia.Lint(testTriviaPath)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	for _, p := range problems {
+		t.Errorf("bundled trivia: %s", p)
+	}
+}
+
+func TestLint_ReportsProblems(t *testing.T) {
+	dir := writeTriviaDir(t, map[string]string{
+		"a.json": `{
+			"Empty": [],
+			"Teams": ["Grizzlies", "grizzlies", " Nets", "Trail  Blazers"],
+			"Capitals": [
+				{"answer": "Saint Paul", "aliases": ["St. Paul", "Salem"]},
+				"Salem"
+			]
+		}`,
+		"b.json":   `{"Teams": ["Hawks"]}`,
+		"bad.json": `{"version": 2, "quizzes": [{"title": "T", "answers": [{"answer": "x", "alias": ["y"]}]}]}`,
+	})
+	problems, err := trivia.Lint(dir)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	want := []string{
+		`a.json: "Empty": quiz has no answers`,
+		`a.json: "Teams": answer "grizzlies" is indistinguishable from "Grizzlies"`,
+		`a.json: "Teams": answer " Nets" has leading or trailing whitespace`,
+		`a.json: "Teams": answer "Trail  Blazers" has repeated spaces`,
+		`a.json: "Capitals": alias "Salem" of "Saint Paul" conflicts with "Salem"`,
+		`b.json: "Teams": title is already used in a.json`,
+		`bad.json: json: unknown field "alias"`,
+	}
+	got := make([]string, 0, len(problems))
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	joined := strings.Join(got, "\n")
+	for _, w := range want {
+		if !strings.Contains(joined, w) {
+			t.Errorf("Lint missing problem %q; got:\n%s", w, joined)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("Lint found %d problems, want %d:\n%s", len(got), len(want), joined)
+	}
+}
+
+func TestLintQuiz_RedundantAliases(t *testing.T) {
+	q := &trivia.Quiz{Title: "T", Answers: []trivia.Answer{
+		{Answer: "Phoenix", Aliases: []string{"phoenix", "PHX", "phx"}},
+	}}
+	problems := trivia.LintQuiz(q)
+	if len(problems) != 2 {
+		t.Fatalf("LintQuiz = %v, want 2 problems", problems)
+	}
+	if !strings.Contains(problems[0], "same as its answer") || !strings.Contains(problems[1], "listed twice") {
+		t.Errorf("LintQuiz = %v", problems)
+	}
+}
+
+func TestValidateCustomQuiz_TrimsWhitespace(t *testing.T) {
+	answers, err := trivia.ValidateCustomQuiz(" Ours ", []trivia.Answer{{Answer: " billing ", Aliases: []string{" bills"}}})
+	if err != nil {
+		t.Fatalf("ValidateCustomQuiz: %v", err)
+	}
+	if answers[0].Item != "billing" || answers[0].Aliases[0] != "bills" {
+		t.Errorf("ValidateCustomQuiz = %+v, want trimmed answers", answers)
+	}
+	if _, err := trivia.ValidateCustomQuiz("  ", []trivia.Answer{{Answer: "billing"}}); err == nil {
+		t.Error("ValidateCustomQuiz with a blank title expected error")
+	}
+}
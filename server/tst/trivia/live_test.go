@@ -0,0 +1,149 @@
+package trivia_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	state "server/state"
+	trivia "server/trivia"
+	test "server/tst"
+)
+
+// rewrite replaces a trivia file and bumps its modification time so polling
+// notices the change even within the filesystem's timestamp granularity.
+func rewrite(t *testing.T, dir, name, data string, when time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	if err := os.Chtimes(path, when, when); err != nil {
+		t.Fatalf("chtimes %s: %v", name, err)
+	}
+}
+
+func TestLiveCatalog_RefreshPicksUpChanges(t *testing.T) {
+	dir := writeTriviaDir(t, map[string]string{
+		"a.json": `{"Colors": ["Red", "Blue"], "Shapes": ["Circle"]}`,
+	})
+	live, err := trivia.OpenLiveCatalog(dir)
+	if err != nil {
+		t.Fatalf("OpenLiveCatalog: %v", err)
+	}
+
+	changes, err := live.Refresh()
+	if err != nil || !changes.Empty() {
+		t.Fatalf("Refresh with no changes = %+v, %v; want empty", changes, err)
+	}
+
+	later := time.Now().Add(time.Minute)
+	rewrite(t, dir, "a.json", `{"Colors": ["Red", "Blue", "Green"]}`, later)
+	rewrite(t, dir, "b.json", `{"Animals": ["Cat"]}`, later)
+	before := live.Current()
+
+	changes, err = live.Refresh()
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if !slices.Equal(changes.Added, []string{"Animals"}) ||
+		!slices.Equal(changes.Changed, []string{"Colors"}) ||
+		!slices.Equal(changes.Removed, []string{"Shapes"}) {
+		t.Errorf("Refresh changes = %+v", changes)
+	}
+	if live.Current() == before {
+		t.Error("Refresh should swap in a new catalog")
+	}
+	if len(before.Quiz("Colors").Answers) != 2 {
+		t.Error("the old catalog must not be modified by a reload")
+	}
+	if len(live.Current().Quiz("Colors").Answers) != 3 {
+		t.Error("the new catalog should have the updated Colors quiz")
+	}
+}
+
+func TestLiveCatalog_RejectedEditKeepsPreviousQuizzes(t *testing.T) {
+	dir := writeTriviaDir(t, map[string]string{
+		"a.json": `{"Colors": ["Red", "Blue"]}`,
+	})
+	live, err := trivia.OpenLiveCatalog(dir)
+	if err != nil {
+		t.Fatalf("OpenLiveCatalog: %v", err)
+	}
+
+	rewrite(t, dir, "a.json", `{"Colors": ["Red",`, time.Now().Add(time.Minute))
+	changes, err := live.Refresh()
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, ok := changes.Rejected["a.json"]; !ok {
+		t.Errorf("Refresh should report a.json as rejected, got %+v", changes)
+	}
+	if len(changes.Removed) != 0 || live.Current().Quiz("Colors") == nil {
+		t.Error("a broken edit should not remove the quizzes that were already loaded")
+	}
+}
+
+func TestLiveCatalog_RunningGamesKeepTheirSnapshot(t *testing.T) {
+	dir := writeTriviaDir(t, map[string]string{
+		"a.json": `{"Colors": ["Red", "Blue"]}`,
+	})
+	live, err := trivia.OpenLiveCatalog(dir)
+	if err != nil {
+		t.Fatalf("OpenLiveCatalog: %v", err)
+	}
+	s := state.NewGlobalStateWithCatalog(live)
+	m := s.Create("Colors", test.LOBBY_TIME, test.GAME_TIME)
+	if m == nil {
+		t.Fatal("Create failed")
+	}
+
+	rewrite(t, dir, "a.json", `{"Colors": ["Red", "Blue", "Green"]}`, time.Now().Add(time.Minute))
+	if _, err := live.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if snap, _ := m.Snapshot(); len(snap.Board) != 2 {
+		t.Errorf("running game Board has %d squares after reload, want 2", len(snap.Board))
+	}
+	m2 := s.Create("Colors", test.LOBBY_TIME, test.GAME_TIME)
+	if m2 == nil {
+		t.Fatal("Create failed after reload")
+	}
+	if snap, _ := m2.Snapshot(); len(snap.Board) != 3 {
+		t.Error("new games should use the reloaded quiz")
+	}
+}
+
+func TestLiveCatalog_ReloadSourcesKeepsDirectoryAsLoaded(t *testing.T) {
+	dir := writeTriviaDir(t, map[string]string{
+		"a.json": `{"Colors": ["Red", "Blue"]}`,
+	})
+	live, err := trivia.OpenLiveCatalog(dir)
+	if err != nil {
+		t.Fatalf("OpenLiveCatalog: %v", err)
+	}
+	extra := []trivia.Quiz{{Title: "Shapes", Answers: []trivia.Answer{{Answer: "Circle"}}}}
+	if _, err := live.SetSource("extra", func() []trivia.Quiz { return extra }); err != nil {
+		t.Fatalf("SetSource: %v", err)
+	}
+
+	// the directory changes, but only the source is reloaded
+	rewrite(t, dir, "a.json", `{"Colors": ["Red", "Blue", "Green"]}`, time.Now().Add(time.Minute))
+	extra = append(extra, trivia.Quiz{Title: "Animals", Answers: []trivia.Answer{{Answer: "Cat"}}})
+	changes := live.ReloadSources()
+	if !slices.Equal(changes.Added, []string{"Animals"}) || len(changes.Changed) != 0 {
+		t.Errorf("ReloadSources changes = %+v, want only Animals added", changes)
+	}
+	if n := len(live.Current().Quiz("Colors").Answers); n != 2 {
+		t.Errorf("Colors has %d answers after ReloadSources, want the 2 loaded from disk", n)
+	}
+
+	if _, err := live.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if live.Current().Quiz("Colors") == nil || len(live.Current().Quiz("Colors").Answers) != 3 || live.Current().Quiz("Animals") == nil {
+		t.Error("Refresh should pick up the directory change and keep the source")
+	}
+}
@@ -0,0 +1,114 @@
+package trivia_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	game "server/game"
+	trivia "server/trivia"
+)
+
+const testTriviaPath = "../../../trivia"
+
+func TestParse_LegacyFormat(t *testing.T) {
+	data := []byte(`{"B Quiz": ["x", "y"], "A Quiz": ["Saint Paul", {"answer": "Phoenix", "aliases": ["PHX"]}]}`)
+	quizzes, err := trivia.Parse(data)
+	if err != nil {
+		t.Fatalf("Parse legacy: %v", err)
+	}
+	if len(quizzes) != 2 || quizzes[0].Title != "A Quiz" || quizzes[1].Title != "B Quiz" {
+		t.Fatalf("Parse legacy: titles = %v, want [A Quiz B Quiz]", trivia.Titles(quizzes))
+	}
+	answers := quizzes[0].Answers
+	if len(answers) != 2 || answers[1].Answer != "Phoenix" || len(answers[1].Aliases) != 1 {
+		t.Errorf("Parse legacy: unexpected answers %+v", answers)
+	}
+}
+
+func TestParse_VersionedFormat(t *testing.T) {
+	data := []byte(`{
+		"version": 2,
+		"quizzes": [{
+			"title": "NFL Teams",
+			"description": "Name every team.",
+			"difficulty": "medium",
+			"author": "tester",
+			"tags": ["sports"],
+			"lobbyTime": 30,
+			"gameTime": 120,
+			"strictness": "lenient",
+			"answers": [
+				{"answer": "49ers", "aliases": ["Niners"], "hint": "Gold rush", "label": "San Francisco 49ers", "group": "NFC West"},
+				"Bears"
+			]
+		}]
+	}`)
+	quizzes, err := trivia.Parse(data)
+	if err != nil {
+		t.Fatalf("Parse versioned: %v", err)
+	}
+	if len(quizzes) != 1 {
+		t.Fatalf("Parse versioned: got %d quizzes, want 1", len(quizzes))
+	}
+	q := quizzes[0]
+	if q.Difficulty != "medium" || q.LobbyTime != 30 || q.GameTime != 120 || q.Strictness != game.StrictnessLenient {
+		t.Errorf("Parse versioned: unexpected metadata %+v", q)
+	}
+	a := q.Answers[0]
+	if a.Label != "San Francisco 49ers" || a.Group != "NFC West" || a.Hint != "Gold rush" {
+		t.Errorf("Parse versioned: unexpected answer %+v", a)
+	}
+	got := q.GameAnswers()
+	if len(got) != 2 || got[0].Item != "49ers" || got[0].Aliases[0] != "Niners" || got[1].Item != "Bears" {
+		t.Errorf("GameAnswers = %+v", got)
+	}
+}
+
+func TestParse_RejectsBadFiles(t *testing.T) {
+	cases := map[string]string{
+		"unknown version":    `{"version": 7, "quizzes": []}`,
+		"unknown field":      `{"version": 2, "quizzes": [{"title": "T", "colour": "red", "answers": ["a"]}]}`,
+		"missing title":      `{"version": 2, "quizzes": [{"answers": ["a"]}]}`,
+		"bad difficulty":     `{"version": 2, "quizzes": [{"title": "T", "difficulty": "extreme", "answers": ["a"]}]}`,
+		"short default time": `{"version": 2, "quizzes": [{"title": "T", "gameTime": 5, "answers": ["a"]}]}`,
+		"bad strictness":     `{"version": 2, "quizzes": [{"title": "T", "strictness": "loose", "answers": ["a"]}]}`,
+		"empty answer":       `{"T": ["a", ""]}`,
+		"not an object":      `["a", "b"]`,
+	}
+	for name, data := range cases {
+		if _, err := trivia.Parse([]byte(data)); err == nil {
+			t.Errorf("Parse %s: expected error", name)
+		}
+	}
+}
+
+func TestLoadFile_BundledTrivia(t *testing.T) {
+	for _, file := range []string{"geography.json", "sports.json"} {
+		quizzes, err := trivia.LoadFile(testTriviaPath + "/" + file)
+		if err != nil {
+			t.Errorf("LoadFile(%s): %v", file, err)
+			continue
+		}
+		if len(quizzes) == 0 {
+			t.Errorf("LoadFile(%s): no quizzes", file)
+		}
+	}
+}
+
+func TestAnswer_UnmarshalStringOrObject(t *testing.T) {
+	var answers []trivia.Answer
+	data := []byte(`["Phoenix", {"answer": "Saint Paul", "aliases": ["St. Paul"]}]`)
+	if err := json.Unmarshal(data, &answers); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(answers) != 2 || answers[0].Answer != "Phoenix" || answers[1].Answer != "Saint Paul" {
+		t.Fatalf("unexpected answers: %+v", answers)
+	}
+	if len(answers[1].Aliases) != 1 || answers[1].Aliases[0] != "St. Paul" {
+		t.Errorf("aliases = %v, want [St. Paul]", answers[1].Aliases)
+	}
+
+	if err := json.Unmarshal([]byte(`[{"answer": "Saint Paul", "alias": ["St. Paul"]}]`), &answers); err == nil {
+		t.Error("misspelled aliases field expected error")
+	}
+}